@@ -0,0 +1,97 @@
+// Package blob stores binary objects, like images, apart from the JSON tables.
+package blob
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrNotFound is returned when no object is stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// Store is a key-value store for binary objects. Keys are slash separated
+// paths, e.g. avatars/1/small.jpg.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	//nolint:gochecknoglobals
+	lock = sync.RWMutex{}
+	//nolint:gochecknoglobals
+	store Store
+)
+
+// Set plugs in the store used by the package level functions.
+func Set(s Store) {
+	lock.Lock()
+	defer lock.Unlock()
+	store = s
+}
+
+func current() Store {
+	lock.RLock()
+	defer lock.RUnlock()
+	return store
+}
+
+func Put(ctx context.Context, key string, data []byte) error {
+	return current().Put(ctx, key, data)
+}
+
+func Get(ctx context.Context, key string) ([]byte, error) {
+	return current().Get(ctx, key)
+}
+
+func Delete(ctx context.Context, key string) error {
+	return current().Delete(ctx, key)
+}
+
+// FileStore stores objects as files under a directory.
+type FileStore struct {
+	Dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+func (f *FileStore) Put(_ context.Context, key string, data []byte) error {
+	path := f.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, readers never see a partial object.
+	tmp := path + ".tmp"
+	//nolint:gosec
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (f *FileStore) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (f *FileStore) Delete(_ context.Context, key string) error {
+	err := os.Remove(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (f *FileStore) path(key string) string {
+	return filepath.Join(f.Dir, filepath.FromSlash(filepath.Clean("/"+key)))
+}
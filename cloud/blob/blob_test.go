@@ -0,0 +1,31 @@
+package blob
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+
+	if err := store.Put(t.Context(), "avatars/1/small.jpg", []byte("image")); err != nil {
+		t.Fatalf("failed to put blob: %v", err)
+	}
+
+	data, err := store.Get(t.Context(), "avatars/1/small.jpg")
+	if err != nil || string(data) != "image" {
+		t.Errorf("got %q and error %v", data, err)
+	}
+
+	// Keys can not escape the store directory.
+	if _, err := store.Get(t.Context(), "../../avatars/1/small.jpg"); err != nil {
+		t.Errorf("expected the key to be kept within the directory, got %v", err)
+	}
+
+	if err := store.Delete(t.Context(), "avatars/1/small.jpg"); err != nil {
+		t.Fatalf("failed to delete blob: %v", err)
+	}
+	if _, err := store.Get(t.Context(), "avatars/1/small.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want %v", err, ErrNotFound)
+	}
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/metrics"
 	"github.com/trebent/zerologr"
 )
 
@@ -29,13 +31,10 @@ var (
 	logger = zerologr.V(10)
 )
 
-func AquireTableLock[T Model]() {
+func AquireTableLock[T Model](ctx context.Context) {
 	tableName := getTableName[T]()
 	logger.Info("acquiring table lock", "table", tableName)
-	val, _ := tableLock.LoadOrStore(tableName, &sync.Mutex{})
-	//nolint:errcheck
-	mutex := val.(*sync.Mutex)
-	mutex.Lock()
+	acquireLock(ctx, tableName, tableName)
 }
 
 func ReleaseTableLock[T Model]() {
@@ -47,14 +46,14 @@ func ReleaseTableLock[T Model]() {
 	mutex.Unlock()
 }
 
-func NextID[T Model]() int {
+func NextID[T Model](ctx context.Context) int {
 	tableName := getTableName[T]()
 	val, _ := idMap.LoadOrStore(tableName, 0)
 
 	if val == 0 {
 		zerologr.Info("next ID value for table was empty, initialising", "table", tableName)
 		//nolint:errcheck
-		es, _ := ReadAll[T]()
+		es, _ := ReadAll[T](ctx)
 		val = len(es)
 		zerologr.Info("next ID set", "next", val)
 	}
@@ -65,8 +64,8 @@ func NextID[T Model]() int {
 	return nextID
 }
 
-func Exists[T Model](entity T) bool {
-	all, err := ReadAll[T]()
+func Exists[T Model](ctx context.Context, entity T) bool {
+	all, err := ReadAll[T](ctx)
 	if err != nil {
 		panic("failed to read all entities: " + err.Error())
 	}
@@ -80,10 +79,12 @@ func Exists[T Model](entity T) bool {
 	return false
 }
 
-func Save[T Model](entity T) error {
+func Save[T Model](ctx context.Context, entity T) error {
 	logger.Info("saving entity", "entity", entity, "table", getTableName[T]())
+	ctx, end := startSpan(ctx, "save", getTableName[T]())
+	defer end()
 
-	all, err := ReadAll[T]()
+	all, err := ReadAll[T](ctx)
 	if err != nil {
 		return err
 	}
@@ -110,15 +111,18 @@ func Save[T Model](entity T) error {
 	}
 
 	logger.Info("entity saved", "entity", entity, "table", getTableName[T](), "count", len(all))
+	metrics.SetTableEntities(getTableName[T](), len(all))
 
 	return nil
 }
 
-func Read[T Model](entity T) (T, error) {
+func Read[T Model](ctx context.Context, entity T) (T, error) {
 	logger.Info("reading entity", "table", getTableName[T]())
+	ctx, end := startSpan(ctx, "read", getTableName[T]())
+	defer end()
 
 	var target T
-	entities, err := ReadAll[T]()
+	entities, err := ReadAll[T](ctx)
 	if err != nil {
 		return target, err
 	}
@@ -134,8 +138,10 @@ func Read[T Model](entity T) (T, error) {
 	return target, nil
 }
 
-func ReadAll[T Model]() ([]T, error) {
+func ReadAll[T Model](ctx context.Context) ([]T, error) {
 	logger.Info("reading all", "table", getTableName[T]())
+	_, end := startSpan(ctx, "read_all", getTableName[T]())
+	defer end()
 
 	if err := tableCheck[T](); err != nil {
 		return nil, err
@@ -152,14 +158,17 @@ func ReadAll[T Model]() ([]T, error) {
 		return nil, err
 	}
 	logger.Info("data read", "count", len(entities), "table", getTableName[T]())
+	metrics.SetTableEntities(getTableName[T](), len(entities))
 
 	return entities, nil
 }
 
-func Delete[T Model](entity T) error {
+func Delete[T Model](ctx context.Context, entity T) error {
 	logger.Info("deleting entity", "entity", entity, "table", getTableName[T]())
+	ctx, end := startSpan(ctx, "delete", getTableName[T]())
+	defer end()
 
-	all, err := ReadAll[T]()
+	all, err := ReadAll[T](ctx)
 	if err != nil {
 		return err
 	}
@@ -187,15 +196,20 @@ func Delete[T Model](entity T) error {
 	if err := os.WriteFile(getTablePath[T](), data, 0o644); err != nil {
 		return err
 	}
+	metrics.SetTableEntities(getTableName[T](), len(all))
 
 	return nil
 }
 
-func Clear[T Model]() error {
+func Clear[T Model](ctx context.Context) error {
 	logger.Info("clearing table", "table", getTableName[T]())
+	_, end := startSpan(ctx, "clear", getTableName[T]())
+	defer end()
+
 	if err := os.Remove(getTablePath[T]()); err != nil {
 		return err
 	}
+	metrics.SetTableEntities(getTableName[T](), 0)
 	return nil
 }
 
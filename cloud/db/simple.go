@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -20,14 +21,12 @@ type Simple interface {
 //nolint:gochecknoglobals,mnd
 var simpleLogger = zerologr.V(10).WithName("simple")
 
-func SimpleAcquire[T Simple](e T) {
+func SimpleAcquire[T Simple](ctx context.Context, e T) {
 	tableName := getSimpleTableName[T](e)
 	simpleLogger.Info("acquiring table lock", "table", tableName)
-	val, _ := tableLock.LoadOrStore(tableName, &sync.Mutex{})
-	//nolint:errcheck
-	mutex := val.(*sync.Mutex)
-	mutex.Lock()
+	acquireLock(ctx, tableName, getSimpleTableType(e))
 }
+
 func SimpleRelease[T Simple](e T) {
 	tableName := getSimpleTableName(e)
 	simpleLogger.Info("releasing table lock", "table", tableName)
@@ -37,8 +36,10 @@ func SimpleRelease[T Simple](e T) {
 	mutex.Unlock()
 }
 
-func SimpleRead[T Simple](e T) ([]T, error) {
+func SimpleRead[T Simple](ctx context.Context, e T) ([]T, error) {
 	simpleLogger.Info("reading all", "table", getSimpleTableName(e))
+	_, end := startSpan(ctx, "simple_read", getSimpleTableType(e))
+	defer end()
 
 	if err := simpleTableCheck(e); err != nil {
 		return nil, err
@@ -59,8 +60,11 @@ func SimpleRead[T Simple](e T) ([]T, error) {
 	return entities, nil
 }
 
-func SimpleAppend[T Simple](entity T) error {
-	es, err := SimpleRead(entity)
+func SimpleAppend[T Simple](ctx context.Context, entity T) error {
+	ctx, end := startSpan(ctx, "simple_append", getSimpleTableType(entity))
+	defer end()
+
+	es, err := SimpleRead(ctx, entity)
 	if err != nil {
 		return err
 	}
@@ -84,8 +88,10 @@ func SimpleAppend[T Simple](entity T) error {
 	return nil
 }
 
-func SimpleClear[T Simple](e T) error {
+func SimpleClear[T Simple](ctx context.Context, e T) error {
 	simpleLogger.Info("clearing table", "table", getSimpleTableName(e))
+	_, end := startSpan(ctx, "simple_clear", getSimpleTableType(e))
+	defer end()
 	if err := os.Remove(getSimpleTablePath(e)); err != nil {
 		if os.IsNotExist(err) {
 			return nil
@@ -96,9 +102,13 @@ func SimpleClear[T Simple](e T) error {
 }
 
 func getSimpleTableName[T Simple](e T) string {
-	return fmt.Sprintf(
-		"%s-%s.json", strings.ReplaceAll(reflect.TypeOf(e).String(), "*", ""), e.TableKey(),
-	)
+	return fmt.Sprintf("%s-%s.json", getSimpleTableType(e), e.TableKey())
+}
+
+// getSimpleTableType is the table name without the table key, used where the
+// per key tables should be aggregated, like in metrics.
+func getSimpleTableType[T Simple](e T) string {
+	return strings.ReplaceAll(reflect.TypeOf(e).String(), "*", "")
 }
 
 func getSimpleTablePath[T Simple](e T) string {
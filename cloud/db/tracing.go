@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/trebent/tapp-backend/metrics"
+	"github.com/trebent/tapp-backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//nolint:gochecknoglobals
+var tracer = tracing.Tracer("db")
+
+// startSpan starts the span and the metrics timer of a DB operation, call the
+// returned function once the operation is done.
+func startSpan(ctx context.Context, operation, table string) (context.Context, func()) {
+	ctx, span := tracer.Start(
+		ctx, "db."+operation, trace.WithAttributes(attribute.String("db.table", table)),
+	)
+	stopTimer := metrics.DBTimer(operation, table)
+	return ctx, func() {
+		stopTimer()
+		span.End()
+	}
+}
+
+// acquireLock takes the lock identified by key, recording the time spent
+// waiting for it. Table is the table label used for the span and metrics.
+func acquireLock(ctx context.Context, key, table string) {
+	ctx, end := startSpan(ctx, "lock", table)
+	defer end()
+
+	start := time.Now()
+	val, _ := tableLock.LoadOrStore(key, &sync.Mutex{})
+	//nolint:errcheck
+	mutex := val.(*sync.Mutex)
+	mutex.Lock()
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int64("db.lock_wait_ms", time.Since(start).Milliseconds()),
+	)
+}
@@ -3,6 +3,7 @@ package env
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/trebent/envparser"
 )
@@ -15,6 +16,213 @@ var (
 		Desc:  "Address to listen on",
 	})
 	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	MetricsAddr = envparser.Register(&envparser.Opts[string]{
+		Value: ":9090",
+		Name:  "METRICS_ADDR",
+		Desc:  "Address to serve Prometheus metrics on, kept apart from ADDR",
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	ShutdownDrainSeconds = envparser.Register(&envparser.Opts[int]{
+		Value: 5,
+		Name:  "SHUTDOWN_DRAIN_SECONDS",
+		Desc:  "Seconds to report not ready before shutting down the server",
+		Validate: func(v int) error {
+			if v < 0 {
+				return fmt.Errorf("value is negative: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	OwnerDeparturePolicy = envparser.Register(&envparser.Opts[string]{
+		Value: "transfer",
+		Name:  "OWNER_DEPARTURE_POLICY",
+		Desc: "What happens to a group when its owner leaves or deletes their account: " +
+			"transfer to the longest-standing member (archiving the group if there is none), " +
+			"or archive",
+		Validate: func(v string) error {
+			if !slices.Contains([]string{"transfer", "archive"}, v) {
+				return fmt.Errorf("unknown owner departure policy: %s", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	MaxGroupMembers = envparser.Register(&envparser.Opts[int]{
+		Value: 50,
+		Name:  "MAX_GROUP_MEMBERS",
+		Desc:  "Maximum number of members in a group, the owner included",
+		Validate: func(v int) error {
+			if v <= 0 {
+				return fmt.Errorf("value is not positive: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	MaxOwnedGroups = envparser.Register(&envparser.Opts[int]{
+		Value: 10,
+		Name:  "MAX_OWNED_GROUPS",
+		Desc:  "Maximum number of groups an account can own",
+		Validate: func(v int) error {
+			if v <= 0 {
+				return fmt.Errorf("value is not positive: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	MaxPendingInvites = envparser.Register(&envparser.Opts[int]{
+		Value: 20,
+		Name:  "MAX_PENDING_INVITES",
+		Desc:  "Maximum number of pending invitations an account can have sent",
+		Validate: func(v int) error {
+			if v <= 0 {
+				return fmt.Errorf("value is not positive: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	GroupRestoreHours = envparser.Register(&envparser.Opts[int]{
+		Value: 720,
+		Name:  "GROUP_RESTORE_HOURS",
+		Desc:  "Hours a deleted group can be restored by its owner before it is purged",
+		Validate: func(v int) error {
+			if v < 0 {
+				return fmt.Errorf("value is negative: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	InvitationTTLHours = envparser.Register(&envparser.Opts[int]{
+		Value: 168,
+		Name:  "INVITATION_TTL_HOURS",
+		Desc:  "Hours an invitation can be accepted before it expires",
+		Validate: func(v int) error {
+			if v <= 0 {
+				return fmt.Errorf("value is not positive: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	InvitationSweepMinutes = envparser.Register(&envparser.Opts[int]{
+		Value: 10,
+		Name:  "INVITATION_SWEEP_MINUTES",
+		Desc:  "Minutes between sweeps removing expired invitations",
+		Validate: func(v int) error {
+			if v <= 0 {
+				return fmt.Errorf("value is not positive: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	TappCooldownSeconds = envparser.Register(&envparser.Opts[int]{
+		Value: 5,
+		Name:  "TAPP_COOLDOWN_SECONDS",
+		Desc:  "Default seconds a member has to wait between tapps of the same group",
+		Validate: func(v int) error {
+			if v < 0 {
+				return fmt.Errorf("value is negative: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	TappRatePerMinute = envparser.Register(&envparser.Opts[int]{
+		Value: 30,
+		Name:  "TAPP_RATE_PER_MINUTE",
+		Desc:  "Default maximum number of tapps a group accepts per minute, from all members",
+		Validate: func(v int) error {
+			if v <= 0 {
+				return fmt.Errorf("value is not positive: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	TappCoalesceSeconds = envparser.Register(&envparser.Opts[int]{
+		Value: 10,
+		Name:  "TAPP_COALESCE_SECONDS",
+		Desc: "Seconds after a tapp push during which further tapps of the same member are " +
+			"collected into a single push, zero disables coalescing",
+		Validate: func(v int) error {
+			if v < 0 {
+				return fmt.Errorf("value is negative: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	OutboxWorkers = envparser.Register(&envparser.Opts[int]{
+		Value: 4,
+		Name:  "OUTBOX_WORKERS",
+		Desc:  "Number of workers delivering push notifications from the outbox",
+		Validate: func(v int) error {
+			if v <= 0 {
+				return fmt.Errorf("value is not positive: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	OutboxMaxAttempts = envparser.Register(&envparser.Opts[int]{
+		Value: 5,
+		Name:  "OUTBOX_MAX_ATTEMPTS",
+		Desc:  "Delivery attempts of a push notification before it is dead-lettered",
+		Validate: func(v int) error {
+			if v <= 0 {
+				return fmt.Errorf("value is not positive: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	DeadLetterHours = envparser.Register(&envparser.Opts[int]{
+		Value: 168,
+		Name:  "DEAD_LETTER_HOURS",
+		Desc:  "Hours a dead-lettered push notification is kept before it is purged",
+		Validate: func(v int) error {
+			if v <= 0 {
+				return fmt.Errorf("value is not positive: %d", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	TappSeenNotify = envparser.Register(&envparser.Opts[bool]{
+		Value: true,
+		Name:  "TAPP_SEEN_NOTIFY",
+		Desc:  "Notify the sender of a tapp once every other member has seen it",
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	InviteLinkBase = envparser.Register(&envparser.Opts[string]{
+		Name: "INVITE_LINK_BASE",
+		Desc: "Base URL of shareable invite links, the invite code is appended to it. " +
+			"No links are generated when empty",
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	TraceExporter = envparser.Register(&envparser.Opts[string]{
+		Value: "none",
+		Name:  "TRACE_EXPORTER",
+		Desc:  "Where to export traces: none, stdout or otlp",
+		Validate: func(v string) error {
+			if !slices.Contains([]string{"none", "stdout", "otlp"}, v) {
+				return fmt.Errorf("unknown trace exporter: %s", v)
+			}
+			return nil
+		},
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
+	OTLPEndpoint = envparser.Register(&envparser.Opts[string]{
+		Value: "localhost:4317",
+		Name:  "OTLP_ENDPOINT",
+		Desc:  "OTLP gRPC collector endpoint, used with TRACE_EXPORTER=otlp",
+	})
+	//nolint:gochecknoglobals // Global vars are fine for env vars.
 	LogToConsole = envparser.Register(&envparser.Opts[bool]{
 		Value: true,
 		Name:  "LOG_TO_CONSOLE",
@@ -43,9 +251,21 @@ var (
 		Name:  "ADMIN_KEY",
 		Desc:  "Administrator key to read data",
 	})
+	Notifier = envparser.Register(&envparser.Opts[string]{
+		Value: "fcm",
+		Name:  "NOTIFIER",
+		Desc: "How push notifications are delivered: fcm sends them through Firebase, " +
+			"log only logs them, and fake records them in memory",
+		Validate: func(v string) error {
+			if !slices.Contains([]string{"fcm", "log", "fake"}, v) {
+				return fmt.Errorf("unknown notifier: %s", v)
+			}
+			return nil
+		},
+	})
 	FirebaseSvcKeyPath = envparser.Register(&envparser.Opts[string]{
-		Required: true,
-		Name:     "FIREBASE_SVC_KEY_PATH",
+		Name: "FIREBASE_SVC_KEY_PATH",
+		Desc: "Path to the Firebase service account key, required by the fcm notifier",
 	})
 )
 
@@ -0,0 +1,187 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/zerologr"
+)
+
+const (
+	KindTappCreated  = "tapp_created"
+	KindMemberJoined = "member_joined"
+	KindMemberLeft   = "member_left"
+	KindMemberKicked = "member_kicked"
+	KindInviteSent   = "invite_sent"
+	KindGroupDeleted = "group_deleted"
+	KindRoleChanged  = "member_role_changed"
+	KindOwnerOffered = "ownership_offered"
+	KindOwnerChanged = "ownership_transferred"
+	KindArchived     = "group_archived"
+	KindTappSeen     = "tapp_seen"
+)
+
+// Event is a domain event published by the handlers once a change has been
+// persisted.
+type Event interface {
+	Kind() string
+	GroupID() int
+}
+
+type (
+	TappCreated struct {
+		Group *model.Group `json:"group"`
+		Tapp  *model.Tapp  `json:"tapp"`
+		// Original is the tapp the tapp answers, if any.
+		Original *model.Tapp `json:"original,omitempty"`
+	}
+	MemberJoined struct {
+		Time    int64          `json:"time"`
+		Group   *model.Group   `json:"group"`
+		Account *model.Account `json:"account"`
+	}
+	MemberLeft struct {
+		Time    int64          `json:"time"`
+		Group   *model.Group   `json:"group"`
+		Account *model.Account `json:"account"`
+	}
+	// MemberKicked is published when By has removed Account from the group.
+	MemberKicked struct {
+		Time    int64          `json:"time"`
+		Group   *model.Group   `json:"group"`
+		Account *model.Account `json:"account"`
+		By      *model.Account `json:"by"`
+	}
+	InviteSent struct {
+		Time    int64          `json:"time"`
+		Group   *model.Group   `json:"group"`
+		Invitee *model.Account `json:"invitee"`
+		Inviter *model.Account `json:"inviter"`
+	}
+	GroupDeleted struct {
+		Time  int64          `json:"time"`
+		Group *model.Group   `json:"group"`
+		By    *model.Account `json:"by"`
+	}
+	// MemberRoleChanged is published when By has given Account a new Role.
+	MemberRoleChanged struct {
+		Time    int64          `json:"time"`
+		Group   *model.Group   `json:"group"`
+		Account *model.Account `json:"account"`
+		Role    model.Role     `json:"role"`
+		By      *model.Account `json:"by"`
+	}
+	// OwnershipOffered is published when the owner From offers the ownership
+	// of the group to the member To.
+	OwnershipOffered struct {
+		Time  int64          `json:"time"`
+		Group *model.Group   `json:"group"`
+		From  *model.Account `json:"from"`
+		To    *model.Account `json:"to"`
+	}
+	OwnershipTransferred struct {
+		Time  int64          `json:"time"`
+		Group *model.Group   `json:"group"`
+		From  *model.Account `json:"from"`
+		To    *model.Account `json:"to"`
+	}
+	GroupArchived struct {
+		Time  int64          `json:"time"`
+		Group *model.Group   `json:"group"`
+		By    *model.Account `json:"by"`
+	}
+	// TappSeen is published once every member but the sender has seen the
+	// tapp.
+	TappSeen struct {
+		Time  int64        `json:"time"`
+		Group *model.Group `json:"group"`
+		Tapp  *model.Tapp  `json:"tapp"`
+	}
+)
+
+var (
+	//nolint:gochecknoglobals
+	lock = sync.RWMutex{}
+	//nolint:gochecknoglobals
+	subscribers = []func(context.Context, Event){}
+	//nolint:gochecknoglobals
+	inFlight = sync.WaitGroup{}
+
+	//nolint:gochecknoglobals,mnd
+	logger = zerologr.V(10).WithName("event")
+)
+
+// Subscribe registers a subscriber for a single event type, e.g.
+// Subscribe(func(ctx context.Context, e *TappCreated) { ... }).
+func Subscribe[T Event](subscriber func(context.Context, T)) {
+	SubscribeAll(func(ctx context.Context, e Event) {
+		if typed, ok := e.(T); ok {
+			subscriber(ctx, typed)
+		}
+	})
+}
+
+// SubscribeAll registers a subscriber that receives every published event.
+func SubscribeAll(subscriber func(context.Context, Event)) {
+	lock.Lock()
+	defer lock.Unlock()
+	subscribers = append(subscribers, subscriber)
+}
+
+// Publish hands the event to every subscriber. Subscribers run in their own
+// goroutines so publishing never blocks the calling handler. They get a copy
+// of ctx that keeps its values, like the trace, but is never canceled.
+func Publish(ctx context.Context, e Event) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	logger.Info("publishing event", "kind", e.Kind(), "group", e.GroupID())
+	ctx = context.WithoutCancel(ctx)
+
+	for _, subscriber := range subscribers {
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					zerologr.Error(
+						fmt.Errorf("%v", r), "event subscriber panicked", "kind", e.Kind(),
+					)
+				}
+			}()
+			subscriber(ctx, e)
+		}()
+	}
+}
+
+// Wait blocks until all subscribers of previously published events have
+// returned.
+func Wait() {
+	inFlight.Wait()
+}
+
+func (*TappCreated) Kind() string          { return KindTappCreated }
+func (*MemberJoined) Kind() string         { return KindMemberJoined }
+func (*MemberLeft) Kind() string           { return KindMemberLeft }
+func (*MemberKicked) Kind() string         { return KindMemberKicked }
+func (*InviteSent) Kind() string           { return KindInviteSent }
+func (*GroupDeleted) Kind() string         { return KindGroupDeleted }
+func (*MemberRoleChanged) Kind() string    { return KindRoleChanged }
+func (*OwnershipOffered) Kind() string     { return KindOwnerOffered }
+func (*OwnershipTransferred) Kind() string { return KindOwnerChanged }
+func (*GroupArchived) Kind() string        { return KindArchived }
+func (*TappSeen) Kind() string             { return KindTappSeen }
+
+func (e *TappCreated) GroupID() int          { return e.Group.ID }
+func (e *MemberJoined) GroupID() int         { return e.Group.ID }
+func (e *MemberLeft) GroupID() int           { return e.Group.ID }
+func (e *MemberKicked) GroupID() int         { return e.Group.ID }
+func (e *InviteSent) GroupID() int           { return e.Group.ID }
+func (e *GroupDeleted) GroupID() int         { return e.Group.ID }
+func (e *MemberRoleChanged) GroupID() int    { return e.Group.ID }
+func (e *OwnershipOffered) GroupID() int     { return e.Group.ID }
+func (e *OwnershipTransferred) GroupID() int { return e.Group.ID }
+func (e *GroupArchived) GroupID() int        { return e.Group.ID }
+func (e *TappSeen) GroupID() int             { return e.Group.ID }
@@ -0,0 +1,62 @@
+package event
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestSubscribeTyped(t *testing.T) {
+	var tapps, joins, all atomic.Int32
+
+	Subscribe(func(context.Context, *TappCreated) { tapps.Add(1) })
+	Subscribe(func(context.Context, *MemberJoined) { joins.Add(1) })
+	SubscribeAll(func(context.Context, Event) { all.Add(1) })
+
+	group := &model.Group{ID: 1}
+	Publish(t.Context(), &TappCreated{Group: group, Tapp: &model.Tapp{GroupID: 1}})
+	Publish(t.Context(), &TappCreated{Group: group, Tapp: &model.Tapp{GroupID: 1}})
+	Publish(t.Context(), &MemberJoined{Group: group, Account: &model.Account{Email: "email"}})
+	Wait()
+
+	if tapps.Load() != 2 {
+		t.Errorf("got %d tapp events, want %d", tapps.Load(), 2)
+	}
+	if joins.Load() != 1 {
+		t.Errorf("got %d join events, want %d", joins.Load(), 1)
+	}
+	if all.Load() != 3 {
+		t.Errorf("got %d events, want %d", all.Load(), 3)
+	}
+}
+
+func TestSubscriberPanic(t *testing.T) {
+	SubscribeAll(func(context.Context, Event) { panic("boom") })
+
+	Publish(t.Context(), &GroupDeleted{Group: &model.Group{ID: 1}})
+	Wait()
+}
+
+func TestActor(t *testing.T) {
+	group := &model.Group{ID: 1}
+	alice, bob := &model.Account{Email: "alice"}, &model.Account{Email: "bob"}
+
+	tests := []struct {
+		event Event
+		want  string
+	}{
+		{event: &TappCreated{Group: group, Tapp: &model.Tapp{User: alice}}, want: "alice"},
+		{event: &MemberKicked{Group: group, Account: bob, By: alice}, want: "alice"},
+		{event: &InviteSent{Group: group, Invitee: bob, Inviter: alice}, want: "alice"},
+		// Groups purged by the server have no actor.
+		{event: &GroupDeleted{Group: group}, want: ""},
+		{event: &TappSeen{Group: group, Tapp: &model.Tapp{User: alice}}, want: ""},
+	}
+	for _, tc := range tests {
+		if got := actor(tc.event); got != tc.want {
+			t.Errorf("%s: got actor %q, want %q", tc.event.Kind(), got, tc.want)
+		}
+	}
+}
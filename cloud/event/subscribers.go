@@ -0,0 +1,53 @@
+package event
+
+import (
+	"context"
+
+	"github.com/trebent/tapp-backend/metrics"
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/tapp-backend/tracing"
+)
+
+// Audit writes an audit log line for every event. Only the kind, group and
+// actor are logged, events carry the whole group with its member emails.
+func Audit(ctx context.Context, e Event) {
+	tracing.Logger(ctx).Info("audit", "kind", e.Kind(), "group", e.GroupID(), "actor", actor(e))
+}
+
+// actor returns the email of the account that caused the event, empty for
+// events the server caused itself.
+func actor(e Event) string {
+	var account *model.Account
+	switch e := e.(type) {
+	case *TappCreated:
+		account = e.Tapp.User
+	case *MemberJoined:
+		account = e.Account
+	case *MemberLeft:
+		account = e.Account
+	case *MemberKicked:
+		account = e.By
+	case *InviteSent:
+		account = e.Inviter
+	case *GroupDeleted:
+		account = e.By
+	case *MemberRoleChanged:
+		account = e.By
+	case *OwnershipOffered:
+		account = e.From
+	case *OwnershipTransferred:
+		account = e.From
+	case *GroupArchived:
+		account = e.By
+	}
+
+	if account == nil {
+		return ""
+	}
+	return account.Email
+}
+
+// Count counts published events in the metrics.
+func Count(_ context.Context, e Event) {
+	metrics.EventPublished(e.Kind())
+}
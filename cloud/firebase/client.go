@@ -3,76 +3,58 @@ package firebase
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
 	"strconv"
 	"sync"
+	"time"
 
-	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/messaging"
+	"github.com/trebent/tapp-backend/db"
 	"github.com/trebent/tapp-backend/env"
 	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/tapp-backend/tracing"
 	"github.com/trebent/zerologr"
-	"google.golang.org/api/option"
 )
 
 var (
 	//nolint:gochecknoglobals
 	fcmLock = sync.Mutex{}
+	// fcmBlob maps emails to the FCM tokens of their sessions, keyed by the
+	// hash of the session token, so that every device an account is logged
+	// in on is notified.
 	//nolint:gochecknoglobals
-	fcmBlob = map[string]string{}
+	fcmBlob = map[string]map[string]string{}
 	//nolint:gochecknoglobals
-	c *messaging.Client
+	notifier Notifier
+	//nolint:gochecknoglobals
+	tracer = tracing.Tracer("firebase")
 )
 
 func Initialize() {
-	ctx := context.Background()
-
 	readBlob()
-	zerologr.Info("loaded FCM blob", "blob", fcmBlob)
-
-	data, _ := os.ReadFile(env.FirebaseSvcKeyPath.Value())
-	zerologr.Info(string(data))
-
-	// Path to the JSON key you downloaded
-	opt := option.WithCredentialsFile(env.FirebaseSvcKeyPath.Value())
+	zerologr.Info("loaded FCM blob", "accounts", len(fcmBlob))
 
-	app, err := firebase.NewApp(ctx, nil, opt)
+	n, err := newNotifier(context.Background(), env.Notifier.Value())
 	if err != nil {
-		zerologr.Error(err, "failed to create new app")
+		zerologr.Error(err, "failed to create notifier")
 		os.Exit(1)
 	}
-
-	client, err := app.Messaging(ctx)
-	if err != nil {
-		zerologr.Error(err, "failed to create messaging app")
-		os.Exit(1)
-	}
-	c = client
-}
-
-func Add(email, fcm string) {
-	zerologr.Info("adding email " + email + " to blob")
-	fcmLock.Lock()
-	defer fcmLock.Unlock()
-
-	fcmBlob[email] = fcm
-
-	writeBlob()
-	zerologr.Info("wrote to FCM blob", "blob", fcmBlob)
+	notifier = n
+	zerologr.Info("using notifier " + env.Notifier.Value())
 }
 
-func Remove(email string) {
-	zerologr.Info("removing email " + email + " from blob")
-	fcmLock.Lock()
-	defer fcmLock.Unlock()
-
-	delete(fcmBlob, email)
-
-	writeBlob()
-	zerologr.Info("wrote to FCM blob", "blob", fcmBlob)
+// Ready reports whether the notifier is initialized and able to send
+// notifications.
+func Ready() error {
+	if notifier == nil {
+		return errors.New("notifier is not initialized")
+	}
+	return nil
 }
 
 /*
@@ -86,6 +68,12 @@ Expected notification DATA:
 	groupId = data["group_id"]!!
 	individual = data["individual"]
 	type = data["type"]!!
+	tappType = data["tapp_type"]
+	emoji = data["emoji"]
+	message = data["message"]
+	tappId = data["tapp_id"]
+	replyTo = data["reply_to"]
+	silent = data["silent"]
 
 NO NOTIFICATION DATA TO PREVENT SYSTEM TRAY HANDLING.
 */
@@ -97,92 +85,150 @@ type TappNotification struct {
 	Group   *model.Group
 	Account *model.Account
 	Type    string
+	// TappType is the kind of tapp pushed, the Type of a tapp push is always
+	// "tapp".
+	TappType string
+	// Emoji and Message are set for tapps carrying them.
+	Emoji   string
+	Message string
+	// TappID is the ID of the pushed tapp and ReplyTo that of the tapp it
+	// answers, if any.
+	TappID  int
+	ReplyTo int
+	// Exclude lists emails that must not receive a multicast.
+	Exclude []string
+	// Mentions lists emails the multicast targets specifically, they are
+	// reached even at the mentions notification level.
+	Mentions []string
 }
 
 // SendIndividual for send invividual, the account is the receiver, and sender.
-func SendIndividual(n *TappNotification) {
+// The quiet hours of the account apply like they do to multicasts.
+func SendIndividual(ctx context.Context, n *TappNotification) {
 	zerologr.Info(
 		fmt.Sprintf(
 			"notifying individual %s with id %d", n.Account.Email, n.Group.ID,
 		),
 	)
 
-	_, err := c.Send(context.Background(), &messaging.Message{
-		Token: getFCM(n.Account),
+	tokens := getFCM(n.Account)
+	if len(tokens) == 0 {
+		zerologr.Info("no FCM tokens to notify individual " + n.Account.Email)
+		return
+	}
+
+	// Accounts without preferences read as nil, they have no quiet hours.
+	prefs, _ := db.Read(ctx, &model.Preferences{Email: n.Account.Email})
+
+	now := time.Now()
+	r := &recipients{}
+	r.add(n.Account.Email, tokens, prefs, now)
+
+	if len(r.loud) > 0 {
+		sendIndividual(ctx, n, r.loud, false)
+	}
+	if len(r.silent) > 0 {
+		sendIndividual(ctx, n, r.silent, true)
+	}
+	if len(r.deferred) > 0 {
+		deferToDigests(ctx, n.Group, r.deferred, now)
+	}
+}
+
+// sendIndividual queues the notification for the tokens of the receiver.
+func sendIndividual(ctx context.Context, n *TappNotification, tokens []string, silent bool) {
+	enqueue(ctx, "individual", &messaging.MulticastMessage{
+		Tokens: tokens,
 		Data: map[string]string{
 			"title":      n.Title,
 			"body":       n.Body,
 			"sender":     n.Account.Email,
 			"sender_tag": n.Account.Tag,
 			"type":       n.Type,
+			"emoji":      n.Emoji,
+			"message":    n.Message,
 			// This is used to display targetted notifications on the client side.
 			"individual": "true",
 			"time":       strconv.Itoa(int(n.Time)),
 			"group_id":   strconv.Itoa(n.Group.ID),
+			"silent":     strconv.FormatBool(silent),
 		},
 	})
-	if err != nil {
-		zerologr.Error(err, "failed to send message")
-		return
-	}
 }
 
 // SendMulticast for send multicast, the account is the sender.
-func SendMulticast(n *TappNotification) {
+func SendMulticast(ctx context.Context, n *TappNotification) {
 	zerologr.Info(
 		fmt.Sprintf(
 			"%s is notifying group %s with id %d", n.Account.Email, n.Group.Name, n.Group.ID,
 		),
 	)
 
-	_, err := c.SendEachForMulticast(context.Background(), &messaging.MulticastMessage{
-		Tokens: getFCMS(n.Group),
+	now := time.Now()
+	r := getFCMS(n.Group, n.Exclude, n.Mentions, preferences(ctx), now)
+
+	if len(r.loud) > 0 {
+		sendMulticast(ctx, n, r.loud, false)
+	}
+	if len(r.silent) > 0 {
+		sendMulticast(ctx, n, r.silent, true)
+	}
+	if len(r.deferred) > 0 {
+		deferToDigests(ctx, n.Group, r.deferred, now)
+	}
+}
+
+// sendMulticast queues the notification for the tokens, silent ones are
+// shown by the client without sound or banner.
+func sendMulticast(ctx context.Context, n *TappNotification, tokens []string, silent bool) {
+	enqueue(ctx, "multicast", &messaging.MulticastMessage{
+		Tokens: tokens,
 		Data: map[string]string{
 			"title":      n.Title,
 			"body":       n.Body,
 			"sender":     n.Account.Email,
 			"sender_tag": n.Account.Tag,
 			"type":       n.Type,
+			"tapp_type":  n.TappType,
+			"emoji":      n.Emoji,
+			"message":    n.Message,
+			"tapp_id":    strconv.Itoa(n.TappID),
+			"reply_to":   strconv.Itoa(n.ReplyTo),
 			"time":       strconv.Itoa(int(n.Time)),
 			"group_id":   strconv.Itoa(n.Group.ID),
+			"silent":     strconv.FormatBool(silent),
 		},
 	})
-	if err != nil {
-		zerologr.Error(err, "failed to send multicast message")
-		return
-	}
-}
-
-func getFCM(account *model.Account) string {
-	fcmLock.Lock()
-	defer fcmLock.Unlock()
-
-	for email, fcm := range fcmBlob {
-		if email == account.Email {
-			return fcm
-		}
-	}
-
-	return ""
 }
 
-func getFCMS(group *model.Group) []string {
+// getFCMS returns the recipients of a multicast to the group, honoring their
+// mute and notification level settings, and the quiet hours of their
+// preferences.
+func getFCMS(
+	group *model.Group,
+	exclude, mentions []string,
+	prefs map[string]*model.Preferences,
+	now time.Time,
+) *recipients {
 	fcmLock.Lock()
 	defer fcmLock.Unlock()
 
-	fcms := []string{}
-	for email, fcm := range fcmBlob {
-		if slices.ContainsFunc(
-			group.Members,
-			func(a *model.Account) bool { return a.Email == email },
-		) || group.Owner == email {
-			fcms = append(fcms, fcm)
+	r := &recipients{loud: []string{}}
+	for email, sessions := range fcmBlob {
+		settings := group.SettingsOf(email)
+		if settings == nil || slices.Contains(exclude, email) ||
+			!settings.Notified(now.UnixMilli(), slices.Contains(mentions, email)) {
+			continue
 		}
+		r.add(email, slices.Sorted(maps.Values(sessions)), prefs[email], now)
 	}
 
-	zerologr.Info("collected FCMs for broadcast", "fcms", fcms)
+	zerologr.Info(
+		"collected FCMs for broadcast",
+		"fcms", len(r.loud), "silent", len(r.silent), "deferred", len(r.deferred),
+	)
 
-	return fcms
+	return r
 }
 
 func readBlob() {
@@ -193,7 +239,7 @@ func readBlob() {
 		//nolint:govet,gosec
 		err := json.Unmarshal(data, &fcmBlob)
 		if err != nil {
-			zerologr.Error(err, "failed to unmarshal FCM blob")
+			readLegacyBlob(data)
 		}
 	}
 }
@@ -204,7 +250,7 @@ func writeBlob() {
 		zerologr.Error(err, "failed to serialize FCM update")
 	} else {
 		//nolint:govet,gosec
-		err := os.WriteFile(filepath.Join(env.FileSystem.Value(), "fcm-blob.json"), data, 0o755)
+		err := os.WriteFile(filepath.Join(env.FileSystem.Value(), "fcm-blob.json"), data, 0o600)
 		if err != nil {
 			zerologr.Error(err, "failed to write file")
 		}
@@ -0,0 +1,89 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/event"
+)
+
+// burst collects the tapps of a member that arrive while the push of an earlier
+// tapp is still fresh.
+type burst struct {
+	count  int
+	latest *event.TappCreated
+}
+
+var (
+	//nolint:gochecknoglobals
+	burstLock = sync.Mutex{}
+	//nolint:gochecknoglobals
+	bursts = map[string]*burst{}
+)
+
+// coalesceTapp pushes the first tapp of a member right away and collects any
+// further tapps of the same member, group and type within the window into a
+// single push once the window has passed. Tapps with a message are never
+// coalesced. Send gets the tapp to push and the number of
+// tapps it stands for. The caller is held for the window, so that pending
+// pushes are sent before shutdown, cut short once stop is closed.
+func coalesceTapp(
+	ctx context.Context,
+	e *event.TappCreated,
+	window time.Duration,
+	stop <-chan struct{},
+	send func(context.Context, *event.TappCreated, int),
+) {
+	if e.Tapp.Message != "" {
+		window = 0
+	}
+	key := fmt.Sprintf(
+		"%d-%s-%s-%s-%d",
+		e.Group.ID,
+		e.Tapp.User.Email,
+		e.Tapp.TypeOrDefault(),
+		e.Tapp.Emoji,
+		e.Tapp.ReplyTo,
+	)
+
+	burstLock.Lock()
+	if b, ok := bursts[key]; ok && window > 0 {
+		b.count++
+		b.latest = e
+		burstLock.Unlock()
+		return
+	}
+	if window > 0 {
+		bursts[key] = &burst{latest: e}
+	}
+	burstLock.Unlock()
+
+	send(ctx, e, 1)
+	if window <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-stop:
+	}
+
+	burstLock.Lock()
+	b := bursts[key]
+	delete(bursts, key)
+	burstLock.Unlock()
+
+	if b.count > 0 {
+		// The summary counts the tapp that was pushed on its own as well.
+		send(ctx, b.latest, b.count+1)
+	}
+}
+
+func tappCoalesceWindow() time.Duration {
+	return time.Duration(env.TappCoalesceSeconds.Value()) * time.Second
+}
@@ -0,0 +1,80 @@
+package firebase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestCoalesceTapp(t *testing.T) {
+	group := &model.Group{ID: 1}
+	tapp := func(email string, time int64) *event.TappCreated {
+		return &event.TappCreated{
+			Group: group,
+			Tapp:  &model.Tapp{Time: time, GroupID: 1, User: &model.Account{Email: email}},
+		}
+	}
+
+	lock := sync.Mutex{}
+	sent := map[int64]int{}
+	send := func(_ context.Context, e *event.TappCreated, count int) {
+		lock.Lock()
+		defer lock.Unlock()
+		sent[e.Tapp.Time] = count
+	}
+
+	wg := sync.WaitGroup{}
+	for i, e := range []*event.TappCreated{
+		tapp("alice", 1), tapp("alice", 2), tapp("bob", 3), tapp("alice", 4),
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			coalesceTapp(t.Context(), e, 100*time.Millisecond, nil, send)
+		}()
+		// Keep the tapps in order.
+		if i == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	wg.Wait()
+
+	// Alice is pushed once on her first tapp and once for the whole burst, Bob
+	// is pushed on his own.
+	if len(sent) != 3 || sent[1] != 1 || sent[3] != 1 || sent[2]+sent[4] != 3 {
+		t.Errorf("unexpected pushes: %v", sent)
+	}
+
+	coalesceTapp(t.Context(), tapp("alice", 5), 0, nil, send)
+	if sent[5] != 1 {
+		t.Errorf("got %d tapps pushed without coalescing, want 1", sent[5])
+	}
+}
+
+func TestCoalesceTappStop(t *testing.T) {
+	group := &model.Group{ID: 1}
+	stop := make(chan struct{})
+	sent := make(chan int, 2)
+	send := func(_ context.Context, _ *event.TappCreated, count int) { sent <- count }
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		coalesceTapp(t.Context(), &event.TappCreated{
+			Group: group,
+			Tapp:  &model.Tapp{GroupID: 1, User: &model.Account{Email: "alice"}},
+		}, time.Hour, stop, send)
+	}()
+	<-sent
+	close(stop)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("coalescing did not stop")
+	}
+}
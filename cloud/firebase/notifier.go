@@ -0,0 +1,164 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/messaging"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/zerologr"
+	"google.golang.org/api/option"
+)
+
+// Notifier delivers push messages. The FCM messaging client is one, the log
+// and fake notifiers stand in for it where there are no credentials.
+type Notifier interface {
+	Send(ctx context.Context, message *messaging.Message) (string, error)
+	SendEachForMulticast(
+		ctx context.Context, message *messaging.MulticastMessage,
+	) (*messaging.BatchResponse, error)
+}
+
+// newNotifier returns the notifier of the kind, as selected by NOTIFIER.
+func newNotifier(ctx context.Context, kind string) (Notifier, error) {
+	switch kind {
+	case "fcm":
+		return newFCM(ctx)
+	case "log":
+		return logNotifier{}, nil
+	case "fake":
+		return NewFake(), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier: %s", kind)
+	}
+}
+
+// newFCM returns a messaging client authenticated by the service account key
+// at FIREBASE_SVC_KEY_PATH.
+func newFCM(ctx context.Context) (*messaging.Client, error) {
+	if env.FirebaseSvcKeyPath.Value() == "" {
+		return nil, errors.New("the fcm notifier needs FIREBASE_SVC_KEY_PATH")
+	}
+
+	opt := option.WithCredentialsFile(env.FirebaseSvcKeyPath.Value())
+
+	app, err := firebase.NewApp(ctx, nil, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new app: %w", err)
+	}
+
+	client, err := app.Messaging(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create messaging app: %w", err)
+	}
+	return client, nil
+}
+
+// logNotifier logs messages instead of sending them, every send succeeds.
+type logNotifier struct{}
+
+func (logNotifier) Send(_ context.Context, message *messaging.Message) (string, error) {
+	zerologr.Info("sending message", "data", message.Data)
+	return "logged", nil
+}
+
+func (logNotifier) SendEachForMulticast(
+	_ context.Context, message *messaging.MulticastMessage,
+) (*messaging.BatchResponse, error) {
+	zerologr.Info("sending multicast", "tokens", len(message.Tokens), "data", message.Data)
+	return succeeded(len(message.Tokens)), nil
+}
+
+// Fake records the messages it is asked to send, so that tests can assert
+// what would have been pushed. Every send succeeds unless Err is set, sends
+// to the Unregistered tokens fail with ErrUnregistered. Only an Err of
+// ErrUnavailable is retried by the outbox.
+type Fake struct {
+	lock       sync.Mutex
+	messages   []*messaging.Message
+	multicasts []*messaging.MulticastMessage
+
+	Err          error
+	Unregistered []string
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) Send(_ context.Context, message *messaging.Message) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.Err != nil {
+		return "", f.Err
+	}
+	f.messages = append(f.messages, message)
+	return "fake", nil
+}
+
+func (f *Fake) SendEachForMulticast(
+	_ context.Context, message *messaging.MulticastMessage,
+) (*messaging.BatchResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	f.multicasts = append(f.multicasts, message)
+
+	response := &messaging.BatchResponse{}
+	for _, token := range message.Tokens {
+		if slices.Contains(f.Unregistered, token) {
+			response.FailureCount++
+			response.Responses = append(response.Responses, &messaging.SendResponse{
+				Error: ErrUnregistered,
+			})
+			continue
+		}
+		response.SuccessCount++
+		response.Responses = append(response.Responses, &messaging.SendResponse{Success: true})
+	}
+	return response, nil
+}
+
+// Messages returns the individual messages sent so far.
+func (f *Fake) Messages() []*messaging.Message {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]*messaging.Message{}, f.messages...)
+}
+
+// Multicasts returns the multicast messages sent so far.
+func (f *Fake) Multicasts() []*messaging.MulticastMessage {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]*messaging.MulticastMessage{}, f.multicasts...)
+}
+
+// Reset forgets the messages sent so far.
+func (f *Fake) Reset() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.messages, f.multicasts = nil, nil
+}
+
+// SetNotifier replaces the notifier, for tests.
+func SetNotifier(n Notifier) {
+	notifier = n
+}
+
+// succeeded returns the response of a multicast to count tokens that all
+// succeeded.
+func succeeded(count int) *messaging.BatchResponse {
+	response := &messaging.BatchResponse{SuccessCount: count}
+	for range count {
+		response.Responses = append(response.Responses, &messaging.SendResponse{Success: true})
+	}
+	return response
+}
@@ -0,0 +1,98 @@
+package firebase
+
+import (
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestNewNotifier(t *testing.T) {
+	env.Parse()
+
+	if n, err := newNotifier(t.Context(), "log"); err != nil || n == nil {
+		t.Errorf("got %v, %v for the log notifier", n, err)
+	}
+	if _, err := newNotifier(t.Context(), "fcm"); err == nil {
+		t.Error("the fcm notifier should need a service account key")
+	}
+	if _, err := newNotifier(t.Context(), "pigeon"); err == nil {
+		t.Error("unknown notifiers should fail")
+	}
+}
+
+func TestFakeNotifier(t *testing.T) {
+	env.Parse()
+	defer db.Clear[*model.OutboxEntry](t.Context())
+	fake := NewFake()
+	SetNotifier(fake)
+	defer SetNotifier(nil)
+
+	fcmBlob = map[string]map[string]string{
+		"owner": {"s1": "owner-token"}, "member": {"s2": "member-token"},
+	}
+	defer func() { fcmBlob = map[string]map[string]string{} }()
+
+	group := &model.Group{ID: 1, Name: "Group 1", Owner: "owner", Members: []*model.Member{{Email: "member"}}}
+	owner := &model.Account{Email: "owner"}
+
+	SendMulticast(t.Context(), &TappNotification{
+		Title: "Group 1 was tapped!", Group: group, Account: owner, Exclude: []string{"owner"},
+	})
+	SendIndividual(t.Context(), &TappNotification{
+		Title: "Invited!", Group: group, Account: &model.Account{Email: "member"},
+	})
+	DrainOutbox(t.Context())
+
+	multicasts := fake.Multicasts()
+	if len(multicasts) != 2 {
+		t.Fatalf("got multicasts %+v", multicasts)
+	}
+	for _, m := range multicasts {
+		if len(m.Tokens) != 1 || m.Tokens[0] != "member-token" || m.Data["group_id"] != "1" {
+			t.Errorf("got multicast %+v", m)
+		}
+	}
+	if multicasts[0].Data["title"] != "Group 1 was tapped!" || multicasts[1].Data["individual"] != "true" {
+		t.Errorf("got data %v and %v", multicasts[0].Data, multicasts[1].Data)
+	}
+
+	fake.Reset()
+	fake.Err = ErrUnavailable
+	SendIndividual(t.Context(), &TappNotification{
+		Title: "Invited!", Group: group, Account: &model.Account{Email: "member"},
+	})
+	DrainOutbox(t.Context())
+	if len(fake.Multicasts()) != 0 {
+		t.Error("failed sends should not be recorded")
+	}
+}
+
+func TestSendTappType(t *testing.T) {
+	env.Parse()
+	defer db.Clear[*model.OutboxEntry](t.Context())
+	fake := NewFake()
+	SetNotifier(fake)
+	defer SetNotifier(nil)
+
+	fcmBlob = map[string]map[string]string{"member": {"s1": "member-token"}}
+	defer func() { fcmBlob = map[string]map[string]string{} }()
+
+	group := &model.Group{ID: 1, Name: "Group 1", Owner: "owner", Members: []*model.Member{{Email: "member"}}}
+	sendTapp(t.Context(), &event.TappCreated{
+		Group: group,
+		Tapp: &model.Tapp{
+			ID: 1, GroupID: 1, Type: model.TappTypeUrgent, User: &model.Account{Email: "owner"},
+		},
+	}, 1)
+	DrainOutbox(t.Context())
+
+	// The client only handles pushes of type tapp as tapps.
+	multicasts := fake.Multicasts()
+	if len(multicasts) != 1 || multicasts[0].Data["type"] != "tapp" ||
+		multicasts[0].Data["tapp_type"] != "urgent" {
+		t.Errorf("got multicasts %+v", multicasts)
+	}
+}
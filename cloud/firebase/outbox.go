@@ -0,0 +1,400 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/v4/errorutils"
+	"firebase.google.com/go/v4/messaging"
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/metrics"
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/tapp-backend/tracing"
+	"github.com/trebent/zerologr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	outboxInterval = time.Second
+	// outboxBackoff is the wait before the first retry, doubling with every
+	// attempt up to outboxMaxBackoff.
+	outboxBackoff    = 2 * time.Second
+	outboxMaxBackoff = 10 * time.Minute
+)
+
+// ErrUnavailable is a send failure worth retrying, for the fake notifier.
+var ErrUnavailable = errors.New("notifier is unavailable")
+
+var (
+	// claimed holds the IDs of the outbox entries being delivered, and
+	// outboxDue the time the earliest of the others is due, zero until the
+	// outbox has been read. Both are guarded by claimedLock.
+	//nolint:gochecknoglobals
+	claimed = map[int]bool{}
+	//nolint:gochecknoglobals
+	outboxDue int64
+	//nolint:gochecknoglobals
+	claimedLock = sync.Mutex{}
+	// outboxNextID is the ID of the next entry, zero until the outbox and the
+	// dead letters have been read. It is guarded by the outbox table lock.
+	//nolint:gochecknoglobals
+	outboxNextID int
+	//nolint:gochecknoglobals
+	outboxWake = make(chan struct{}, 1)
+	//nolint:gochecknoglobals
+	outboxWorkers = sync.WaitGroup{}
+)
+
+// enqueue stores the message in the outbox, to be delivered by RunOutbox. A
+// message that cannot be stored is delivered right away instead.
+func enqueue(ctx context.Context, kind string, message *messaging.MulticastMessage) {
+	now := time.Now()
+	entry := &model.OutboxEntry{
+		Kind:        kind,
+		Tokens:      message.Tokens,
+		Data:        message.Data,
+		Created:     now.UnixMilli(),
+		NextAttempt: now.UnixMilli(),
+	}
+
+	db.AquireTableLock[*model.OutboxEntry](ctx)
+	id, err := nextOutboxID(ctx)
+	if err == nil {
+		entry.ID = id
+		err = db.Save(ctx, entry)
+	}
+	db.ReleaseTableLock[*model.OutboxEntry]()
+
+	if err != nil {
+		tracing.Logger(ctx).Error(err, "failed to store push in outbox, sending it directly")
+		deliver(ctx, entry)
+		return
+	}
+
+	dueAt(entry.NextAttempt)
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// RunOutbox delivers the due outbox entries with a pool of OUTBOX_WORKERS
+// workers, until the context is done. Entries are stored, so pushes not yet
+// delivered survive restarts, and entries not yet handed to a worker are left
+// to DrainOutbox.
+func RunOutbox(ctx context.Context) {
+	// Deliveries in progress are finished rather than cancelled on shutdown.
+	deliverCtx := context.WithoutCancel(ctx)
+
+	jobs := make(chan *model.OutboxEntry)
+	for range env.OutboxWorkers.Value() {
+		outboxWorkers.Add(1)
+		go func() {
+			defer outboxWorkers.Done()
+			for entry := range jobs {
+				deliver(deliverCtx, entry)
+			}
+		}()
+	}
+	defer close(jobs)
+
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+
+	for {
+		due := claimDue(ctx, time.Now())
+		for i, entry := range due {
+			select {
+			case jobs <- entry:
+			case <-ctx.Done():
+				for _, entry := range due[i:] {
+					requeue(entry)
+				}
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-outboxWake:
+		}
+	}
+}
+
+// DrainOutbox waits for the workers of RunOutbox to finish their deliveries,
+// then makes one more attempt at every pending entry regardless of its
+// backoff. Entries still failing, or not attempted before the context is done,
+// are kept for the next start.
+func DrainOutbox(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		outboxWorkers.Wait()
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		zerologr.Info("outbox workers did not finish before shutdown")
+		return
+	}
+
+	entries := claimDue(ctx, time.Now().Add(outboxMaxBackoff))
+	zerologr.Info("draining outbox", "entries", len(entries))
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			requeue(entry)
+			continue
+		}
+		deliver(ctx, entry)
+	}
+}
+
+// nextOutboxID returns the ID of a new entry, above those of the outbox and
+// the dead letters. The caller holds the outbox table lock.
+func nextOutboxID(ctx context.Context) (int, error) {
+	if outboxNextID == 0 {
+		entries, err := db.ReadAll[*model.OutboxEntry](ctx)
+		if err != nil {
+			return 0, err
+		}
+		db.AquireTableLock[*model.DeadLetter](ctx)
+		dead, err := db.ReadAll[*model.DeadLetter](ctx)
+		db.ReleaseTableLock[*model.DeadLetter]()
+		if err != nil {
+			return 0, err
+		}
+
+		outboxNextID = 1
+		for _, entry := range entries {
+			outboxNextID = max(outboxNextID, entry.ID+1)
+		}
+		for _, letter := range dead {
+			outboxNextID = max(outboxNextID, letter.ID+1)
+		}
+	}
+
+	outboxNextID++
+	return outboxNextID - 1, nil
+}
+
+// claimDue returns the outbox entries due at the time that no worker is
+// delivering, and claims them. The outbox is only read once an entry is due.
+func claimDue(ctx context.Context, at time.Time) []*model.OutboxEntry {
+	claimedLock.Lock()
+	skip := outboxDue > at.UnixMilli()
+	claimedLock.Unlock()
+	if skip {
+		return nil
+	}
+
+	db.AquireTableLock[*model.OutboxEntry](ctx)
+	defer db.ReleaseTableLock[*model.OutboxEntry]()
+
+	entries, err := db.ReadAll[*model.OutboxEntry](ctx)
+	if err != nil {
+		tracing.Logger(ctx).Error(err, "failed to read outbox")
+		return nil
+	}
+
+	claimedLock.Lock()
+	defer claimedLock.Unlock()
+
+	// Entries being delivered count too, they may be kept for a retry.
+	due := []*model.OutboxEntry{}
+	outboxDue = math.MaxInt64
+	for _, entry := range entries {
+		switch {
+		case claimed[entry.ID] || entry.NextAttempt > at.UnixMilli():
+			outboxDue = min(outboxDue, entry.NextAttempt)
+		default:
+			claimed[entry.ID] = true
+			due = append(due, entry)
+		}
+	}
+	return due
+}
+
+func unclaim(entry *model.OutboxEntry) {
+	claimedLock.Lock()
+	defer claimedLock.Unlock()
+	delete(claimed, entry.ID)
+}
+
+// requeue releases an entry that was claimed but not delivered.
+func requeue(entry *model.OutboxEntry) {
+	unclaim(entry)
+	dueAt(entry.NextAttempt)
+}
+
+// dueAt makes claimDue read the outbox again by the time.
+func dueAt(at int64) {
+	claimedLock.Lock()
+	defer claimedLock.Unlock()
+	outboxDue = min(outboxDue, at)
+}
+
+// deliver makes one attempt at sending the entry. Tokens reported invalid are
+// pruned, the ones that failed for a passing reason are retried later and the
+// others dead-lettered.
+func deliver(ctx context.Context, entry *model.OutboxEntry) {
+	defer unclaim(entry)
+
+	ctx, span := tracer.Start(ctx, "fcm.SendEachForMulticast", trace.WithAttributes(
+		attribute.String("fcm.kind", entry.Kind),
+		attribute.Int("fcm.tokens", len(entry.Tokens)),
+		attribute.Int("fcm.attempt", entry.Attempts+1),
+	))
+	defer span.End()
+
+	retry, dead := []string{}, []string{}
+	response, err := notifier.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+		Tokens: entry.Tokens,
+		Data:   entry.Data,
+	})
+	if err != nil {
+		metrics.FCMSent(entry.Kind, 0, len(entry.Tokens))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "send failed")
+		if retryable(err) {
+			retry = entry.Tokens
+		} else {
+			dead = entry.Tokens
+		}
+	} else {
+		metrics.FCMSent(entry.Kind, response.SuccessCount, response.FailureCount)
+		span.SetAttributes(
+			attribute.Int("fcm.success_count", response.SuccessCount),
+			attribute.Int("fcm.failure_count", response.FailureCount),
+		)
+
+		invalid := []string{}
+		for i, r := range response.Responses {
+			switch {
+			case i >= len(entry.Tokens) || r.Success:
+			case invalidToken(r.Error):
+				invalid = append(invalid, entry.Tokens[i])
+			case retryable(r.Error):
+				retry = append(retry, entry.Tokens[i])
+				err = r.Error
+			default:
+				dead = append(dead, entry.Tokens[i])
+				err = r.Error
+			}
+		}
+		if len(invalid) > 0 {
+			prune(invalid)
+		}
+	}
+
+	settle(ctx, entry, retry, dead, err)
+}
+
+// retryable returns true if the error of a send is expected to pass, like FCM
+// being unavailable or over quota, or the request not reaching it at all.
+func retryable(err error) bool {
+	if messaging.IsUnavailable(err) || messaging.IsInternal(err) ||
+		messaging.IsQuotaExceeded(err) || errors.Is(err, ErrUnavailable) {
+		return true
+	}
+	// Transport errors carry no response.
+	return errorutils.HTTPResponse(err) == nil && (errorutils.IsDeadlineExceeded(err) ||
+		errorutils.IsUnavailable(err) || errorutils.IsUnknown(err))
+}
+
+// settle removes the delivered entry from the outbox, or schedules a retry
+// for the tokens that failed for a passing reason. The dead tokens, and the
+// retries of entries out of attempts, are moved to the dead letters.
+func settle(ctx context.Context, entry *model.OutboxEntry, retry, dead []string, err error) {
+	db.AquireTableLock[*model.OutboxEntry](ctx)
+	defer db.ReleaseTableLock[*model.OutboxEntry]()
+
+	if len(retry) > 0 || len(dead) > 0 {
+		entry.Attempts++
+		entry.LastError = err.Error()
+		if len(retry) > 0 && entry.Attempts >= env.OutboxMaxAttempts.Value() {
+			dead = append(dead, retry...)
+			retry = nil
+		}
+	}
+	if len(dead) > 0 {
+		tracing.Logger(ctx).Error(err, "dead-lettering push", "entry", entry.ID)
+		metrics.FCMDeadLettered(entry.Kind)
+		deadLetter(ctx, entry, dead)
+	}
+
+	if len(retry) == 0 {
+		//nolint:gosec,govet
+		if err := db.Delete(ctx, entry); err != nil && db.Exists(ctx, entry) {
+			tracing.Logger(ctx).Error(err, "failed to delete delivered push", "entry", entry.ID)
+		}
+		return
+	}
+
+	entry.Tokens = retry
+	entry.NextAttempt = time.Now().Add(backoff(entry.Attempts)).UnixMilli()
+	tracing.Logger(ctx).Info(
+		"retrying push", "entry", entry.ID, "attempts", entry.Attempts, "error", err.Error(),
+	)
+
+	//nolint:gosec,govet
+	if err := db.Save(ctx, entry); err != nil {
+		tracing.Logger(ctx).Error(err, "failed to save push for retry", "entry", entry.ID)
+	}
+	dueAt(entry.NextAttempt)
+}
+
+// deadLetter keeps the tokens of the entry as dead, adding to the tokens of
+// an earlier attempt at it, and purges the dead letters past
+// DEAD_LETTER_HOURS.
+func deadLetter(ctx context.Context, entry *model.OutboxEntry, tokens []string) {
+	db.AquireTableLock[*model.DeadLetter](ctx)
+	defer db.ReleaseTableLock[*model.DeadLetter]()
+
+	now := time.Now()
+	letter := &model.DeadLetter{OutboxEntry: *entry, Died: now.UnixMilli()}
+	if earlier, err := db.Read(ctx, letter); err == nil {
+		tokens = append(earlier.Tokens, tokens...)
+	}
+	letter.Tokens = tokens
+
+	//nolint:gosec,govet
+	if err := db.Save(ctx, letter); err != nil {
+		tracing.Logger(ctx).Error(err, "failed to save dead letter", "entry", entry.ID)
+	}
+
+	letters, err := db.ReadAll[*model.DeadLetter](ctx)
+	if err != nil {
+		tracing.Logger(ctx).Error(err, "failed to read dead letters")
+		return
+	}
+	expiry := now.Add(-time.Duration(env.DeadLetterHours.Value()) * time.Hour).UnixMilli()
+	for _, expired := range letters {
+		if expired.Died >= expiry {
+			continue
+		}
+		//nolint:gosec,govet
+		if err := db.Delete(ctx, expired); err != nil {
+			tracing.Logger(ctx).Error(err, "failed to purge dead letter", "entry", expired.ID)
+		}
+	}
+}
+
+// backoff returns the wait before the retry following the attempts.
+func backoff(attempts int) time.Duration {
+	wait := outboxBackoff
+	for range attempts - 1 {
+		wait *= 2
+		if wait >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return wait
+}
@@ -0,0 +1,151 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestOutboxRetries(t *testing.T) {
+	env.Parse()
+	defer db.Clear[*model.OutboxEntry](t.Context())
+	fake := NewFake()
+	fake.Err = ErrUnavailable
+	SetNotifier(fake)
+	defer SetNotifier(nil)
+
+	enqueue(t.Context(), "individual", &messaging.MulticastMessage{
+		Tokens: []string{"token"}, Data: map[string]string{"title": "Hello"},
+	})
+
+	for _, entry := range claimDue(t.Context(), time.Now()) {
+		deliver(t.Context(), entry)
+	}
+	entries, _ := db.ReadAll[*model.OutboxEntry](t.Context())
+	if len(entries) != 1 || entries[0].Attempts != 1 || entries[0].LastError != ErrUnavailable.Error() {
+		t.Fatalf("got outbox %+v after a failed attempt", entries)
+	}
+	if len(claimDue(t.Context(), time.Now())) != 0 {
+		t.Error("entries should back off after a failed attempt")
+	}
+
+	fake.Err = nil
+	DrainOutbox(t.Context())
+	entries, _ = db.ReadAll[*model.OutboxEntry](t.Context())
+	if len(entries) != 0 || len(fake.Multicasts()) != 1 {
+		t.Errorf("got outbox %+v and %d sends after draining", entries, len(fake.Multicasts()))
+	}
+}
+
+func TestOutboxDeadLetter(t *testing.T) {
+	env.Parse()
+	defer db.Clear[*model.OutboxEntry](t.Context())
+	defer db.Clear[*model.DeadLetter](t.Context())
+	fake := NewFake()
+	SetNotifier(fake)
+	defer SetNotifier(nil)
+
+	// Expired dead letters are purged as new ones come in.
+	expired := &model.DeadLetter{OutboxEntry: model.OutboxEntry{ID: 1000}, Died: 1}
+	if err := db.Save(t.Context(), expired); err != nil {
+		t.Fatalf("failed to save dead letter: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		attempts int
+	}{
+		{name: "out of attempts", err: ErrUnavailable, attempts: env.OutboxMaxAttempts.Value()},
+		{name: "rejected", err: errors.New("invalid argument"), attempts: 1},
+	}
+	for _, tc := range tests {
+		fake.Err = tc.err
+		enqueue(t.Context(), "multicast", &messaging.MulticastMessage{Tokens: []string{"token"}})
+		for range env.OutboxMaxAttempts.Value() + 1 {
+			DrainOutbox(t.Context())
+		}
+
+		entries, _ := db.ReadAll[*model.OutboxEntry](t.Context())
+		if len(entries) != 0 {
+			t.Errorf("%s: got outbox %+v, want the push dead-lettered", tc.name, entries)
+		}
+		letters, _ := db.ReadAll[*model.DeadLetter](t.Context())
+		if len(letters) != 1 || letters[0].Attempts != tc.attempts ||
+			letters[0].LastError != tc.err.Error() {
+			t.Errorf("%s: got dead letters %+v", tc.name, letters)
+		}
+
+		db.Clear[*model.DeadLetter](t.Context())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1: 2 * time.Second, 2: 4 * time.Second, 5: 32 * time.Second, 20: outboxMaxBackoff,
+	} {
+		if got := backoff(attempts); got != want {
+			t.Errorf("got backoff %s after %d attempts, want %s", got, attempts, want)
+		}
+	}
+}
+
+func TestRunOutbox(t *testing.T) {
+	env.Parse()
+	defer db.Clear[*model.OutboxEntry](t.Context())
+	fake := NewFake()
+	SetNotifier(fake)
+	defer SetNotifier(nil)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RunOutbox(ctx)
+	}()
+
+	for range 3 {
+		enqueue(t.Context(), "multicast", &messaging.MulticastMessage{Tokens: []string{"token"}})
+	}
+	for deadline := time.Now().Add(2 * time.Second); len(fake.Multicasts()) < 3; {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d sends, want 3", len(fake.Multicasts()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+	DrainOutbox(t.Context())
+
+	entries, _ := db.ReadAll[*model.OutboxEntry](t.Context())
+	if len(entries) != 0 || len(fake.Multicasts()) != 3 {
+		t.Errorf("got outbox %+v and %d sends", entries, len(fake.Multicasts()))
+	}
+}
+
+func TestDrainOutboxTimeout(t *testing.T) {
+	// A worker stuck on a hanging send.
+	outboxWorkers.Add(1)
+	defer outboxWorkers.Done()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		DrainOutbox(ctx)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("draining did not stop with its context")
+	}
+}
@@ -0,0 +1,146 @@
+package firebase
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/tapp-backend/tracing"
+	"github.com/trebent/zerologr"
+)
+
+const digestInterval = time.Minute
+
+// recipients of a multicast, split by how their quiet hours treat it. Loud
+// and silent hold tokens, deferred the emails whose push waits for a digest.
+type recipients struct {
+	loud     []string
+	silent   []string
+	deferred []string
+}
+
+// add decides how the push reaches the tokens of the recipient at now.
+func (r *recipients) add(email string, fcms []string, prefs *model.Preferences, now time.Time) {
+	if prefs == nil || !prefs.Quiet(now) {
+		r.loud = append(r.loud, fcms...)
+		return
+	}
+
+	switch prefs.Mode() {
+	case model.QuietDrop:
+	case model.QuietDigest:
+		r.deferred = append(r.deferred, email)
+	default:
+		r.silent = append(r.silent, fcms...)
+	}
+}
+
+// preferences returns the notification preferences of all accounts by email.
+func preferences(ctx context.Context) map[string]*model.Preferences {
+	all, err := db.ReadAll[*model.Preferences](ctx)
+	if err != nil {
+		tracing.Logger(ctx).Error(err, "failed to read preferences")
+		return nil
+	}
+
+	prefs := make(map[string]*model.Preferences, len(all))
+	for _, p := range all {
+		prefs[p.Email] = p
+	}
+	return prefs
+}
+
+// deferToDigests counts a push from the group in the digests of the emails.
+func deferToDigests(ctx context.Context, group *model.Group, emails []string, now time.Time) {
+	db.AquireTableLock[*model.Digest](ctx)
+	defer db.ReleaseTableLock[*model.Digest]()
+
+	for _, email := range emails {
+		digest, err := db.Read(ctx, &model.Digest{Email: email})
+		if err != nil {
+			digest = &model.Digest{Email: email}
+		}
+		digest.Add(group, now.UnixMilli())
+
+		//nolint:gosec,govet
+		if err := db.Save(ctx, digest); err != nil {
+			tracing.Logger(ctx).Error(err, "failed to save digest", "email", email)
+		}
+	}
+}
+
+// SendDigests delivers the digests of the accounts whose quiet hours have
+// ended, until the context is done.
+func SendDigests(ctx context.Context) {
+	ticker := time.NewTicker(digestInterval)
+	defer ticker.Stop()
+
+	for {
+		sendDigests(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDigests delivers and deletes the digests of the accounts not in their
+// quiet hours at now.
+func sendDigests(ctx context.Context, now time.Time) {
+	ctx, span := tracer.Start(ctx, "send digests")
+	defer span.End()
+
+	db.AquireTableLock[*model.Digest](ctx)
+	defer db.ReleaseTableLock[*model.Digest]()
+
+	digests, err := db.ReadAll[*model.Digest](ctx)
+	if err != nil {
+		tracing.Logger(ctx).Error(err, "failed to read digests")
+		return
+	}
+
+	prefs := preferences(ctx)
+	for _, digest := range digests {
+		if p := prefs[digest.Email]; p != nil && p.Quiet(now) {
+			continue
+		}
+
+		sendDigest(ctx, digest, now)
+		_ = db.Delete(ctx, digest)
+	}
+}
+
+// sendDigest queues the summary of the digest for its account. A digest of an
+// account without tokens is dropped. The digest has no sender or group, but
+// every key the client expects is set.
+func sendDigest(ctx context.Context, digest *model.Digest, now time.Time) {
+	tokens := getFCM(&model.Account{Email: digest.Email})
+	if len(tokens) == 0 {
+		zerologr.Info("dropping digest without FCM tokens", "email", digest.Email)
+		return
+	}
+
+	enqueue(ctx, "digest", &messaging.MulticastMessage{
+		Tokens: tokens,
+		Data: map[string]string{
+			"title":      "While you were away",
+			"body":       digest.Summary(),
+			"sender":     "",
+			"sender_tag": "",
+			"type":       "digest",
+			"emoji":      "",
+			"message":    "",
+			"tapp_id":    "",
+			"reply_to":   "",
+			"individual": "true",
+			"time":       strconv.FormatInt(now.UnixMilli(), 10),
+			"group_id":   "",
+			"silent":     "false",
+		},
+	})
+}
@@ -0,0 +1,131 @@
+package firebase
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestGetFCMSQuietHours(t *testing.T) {
+	fcmBlob = map[string]map[string]string{
+		"owner": {"s1": "owner-token"}, "awake": {"s2": "awake-token"}, "silent": {"s3": "silent-token"},
+		"drop": {"s4": "drop-token"}, "digest": {"s5": "digest-token"},
+	}
+	defer func() { fcmBlob = map[string]map[string]string{} }()
+
+	group := &model.Group{Owner: "owner", Members: []*model.Member{
+		{Email: "awake"}, {Email: "silent"}, {Email: "drop"}, {Email: "digest"},
+	}}
+	night := func(email string, mode model.QuietMode) *model.Preferences {
+		return &model.Preferences{Email: email, QuietStart: "22:00", QuietEnd: "07:00", QuietMode: mode}
+	}
+	prefs := map[string]*model.Preferences{
+		"awake":  {Email: "awake", Timezone: "Asia/Tokyo", QuietStart: "22:00", QuietEnd: "07:00"},
+		"silent": night("silent", ""),
+		"drop":   night("drop", model.QuietDrop),
+		"digest": night("digest", model.QuietDigest),
+	}
+
+	// 03:00 UTC is noon in Tokyo.
+	r := getFCMS(group, []string{"owner"}, nil, prefs, time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC))
+
+	if !slices.Equal(r.loud, []string{"awake-token"}) {
+		t.Errorf("got loud %v", r.loud)
+	}
+	if !slices.Equal(r.silent, []string{"silent-token"}) {
+		t.Errorf("got silent %v", r.silent)
+	}
+	if !slices.Equal(r.deferred, []string{"digest"}) {
+		t.Errorf("got deferred %v", r.deferred)
+	}
+
+	r = getFCMS(group, nil, nil, prefs, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	if len(r.loud) != 5 || len(r.silent)+len(r.deferred) != 0 {
+		t.Errorf("got loud %v, silent %v, deferred %v during the day", r.loud, r.silent, r.deferred)
+	}
+}
+
+func TestSendDigest(t *testing.T) {
+	env.Parse()
+	defer db.Clear[*model.OutboxEntry](t.Context())
+	fake := NewFake()
+	SetNotifier(fake)
+	defer SetNotifier(nil)
+
+	fcmBlob = map[string]map[string]string{"alice": {"s1": "alice-token"}}
+	defer func() { fcmBlob = map[string]map[string]string{} }()
+
+	digest := &model.Digest{Email: "alice"}
+	digest.Add(&model.Group{ID: 1, Name: "Friends"}, 1)
+	sendDigest(t.Context(), digest, time.Now())
+	DrainOutbox(t.Context())
+
+	multicasts := fake.Multicasts()
+	if len(multicasts) != 1 {
+		t.Fatalf("got multicasts %+v", multicasts)
+	}
+	// The client requires these keys of every push.
+	for _, key := range []string{"title", "body", "sender", "sender_tag", "time", "group_id", "type"} {
+		if _, ok := multicasts[0].Data[key]; !ok {
+			t.Errorf("digest push has no %s", key)
+		}
+	}
+}
+
+func TestSendIndividualQuietHours(t *testing.T) {
+	env.Parse()
+	defer db.Clear[*model.OutboxEntry](t.Context())
+	defer db.Clear[*model.Preferences](t.Context())
+	defer db.Clear[*model.Digest](t.Context())
+	fake := NewFake()
+	SetNotifier(fake)
+	defer SetNotifier(nil)
+
+	fcmBlob = map[string]map[string]string{"alice": {"s1": "alice-token"}}
+	defer func() { fcmBlob = map[string]map[string]string{} }()
+
+	now := time.Now().UTC()
+	group := &model.Group{ID: 1, Name: "Friends"}
+	tests := []struct {
+		mode    model.QuietMode
+		sent    int
+		silent  string
+		digests int
+	}{
+		{mode: model.QuietSilent, sent: 1, silent: "true"},
+		{mode: model.QuietDrop},
+		{mode: model.QuietDigest, digests: 1},
+	}
+	for _, tc := range tests {
+		prefs := &model.Preferences{
+			Email:      "alice",
+			QuietStart: now.Add(-time.Hour).Format("15:04"),
+			QuietEnd:   now.Add(time.Hour).Format("15:04"),
+			QuietMode:  tc.mode,
+		}
+		if err := db.Save(t.Context(), prefs); err != nil {
+			t.Fatalf("failed to save preferences: %v", err)
+		}
+
+		SendIndividual(t.Context(), &TappNotification{
+			Title: "Invited!", Group: group, Account: &model.Account{Email: "alice"},
+		})
+		DrainOutbox(t.Context())
+
+		multicasts := fake.Multicasts()
+		if len(multicasts) != tc.sent || tc.sent > 0 && multicasts[0].Data["silent"] != tc.silent {
+			t.Errorf("%s: got multicasts %+v", tc.mode, multicasts)
+		}
+		digests, _ := db.ReadAll[*model.Digest](t.Context())
+		if len(digests) != tc.digests {
+			t.Errorf("%s: got digests %+v", tc.mode, digests)
+		}
+
+		fake.Reset()
+		db.Clear[*model.Digest](t.Context())
+	}
+}
@@ -0,0 +1,237 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/tapp-backend/tracing"
+)
+
+// blockers returns the emails of the users that have blocked the email.
+func blockers(ctx context.Context, email string) []string {
+	blocklists, err := db.ReadAll[*model.Blocklist](ctx)
+	if err != nil {
+		tracing.Logger(ctx).Error(err, "failed to read blocklists")
+		return nil
+	}
+
+	emails := []string{}
+	for _, blocklist := range blocklists {
+		if blocklist.Blocks(email) {
+			emails = append(emails, blocklist.Email)
+		}
+	}
+	return emails
+}
+
+// mentions returns the emails of the group members mentioned by tag in the
+// message of the tapp, and of the member whose tapp it answers.
+func mentions(ctx context.Context, e *event.TappCreated) []string {
+	group, tapp := e.Group, e.Tapp
+
+	emails := []string{}
+	if e.Original != nil {
+		emails = append(emails, e.Original.User.Email)
+	}
+	for _, tag := range tapp.MentionedTags() {
+		accountTag, err := db.Read(ctx, &model.AccountTag{Tag: tag})
+		if err == nil && group.IsMember(accountTag.Email) {
+			emails = append(emails, accountTag.Email)
+		}
+	}
+	return emails
+}
+
+// sendTapp pushes the tapp to the group, count is the number of tapps of the
+// member the push stands for.
+func sendTapp(ctx context.Context, e *event.TappCreated, count int) {
+	sender := e.Tapp.User.UserIdentifier()
+
+	var title string
+	switch e.Tapp.TypeOrDefault() {
+	case model.TappTypeTappBack:
+		title = fmt.Sprintf("Group %s was tapped back!", e.Group.Name)
+	case model.TappTypeUrgent:
+		title = fmt.Sprintf("Urgent tapp in group %s!", e.Group.Name)
+	case model.TappTypeEmoji:
+		title = fmt.Sprintf("Group %s got a %s!", e.Group.Name, e.Tapp.Emoji)
+	default:
+		title = fmt.Sprintf("Group %s was tapped!", e.Group.Name)
+	}
+
+	body := fmt.Sprintf("%s tapped group %s, tapp them back!", sender, e.Group.Name)
+	switch {
+	case e.Original != nil && e.Tapp.Message == "":
+		body = fmt.Sprintf(
+			"%s answered the tapp of %s in group %s!",
+			sender,
+			e.Original.User.UserIdentifier(),
+			e.Group.Name,
+		)
+	case e.Tapp.Message != "":
+		body = fmt.Sprintf("%s: %s", sender, e.Tapp.Message)
+	case count > 1:
+		body = fmt.Sprintf(
+			"%s tapped group %s %d times, tapp them back!", sender, e.Group.Name, count,
+		)
+	}
+
+	SendMulticast(ctx, &TappNotification{
+		Title:    title,
+		Body:     body,
+		Time:     e.Tapp.Time,
+		Group:    e.Group,
+		Account:  e.Tapp.User,
+		Type:     "tapp",
+		TappType: string(e.Tapp.TypeOrDefault()),
+		Emoji:    e.Tapp.Emoji,
+		Message:  e.Tapp.Message,
+		Exclude:  blockers(ctx, e.Tapp.User.Email),
+		TappID:   e.Tapp.ID,
+		ReplyTo:  e.Tapp.ReplyTo,
+		Mentions: mentions(ctx, e),
+	})
+}
+
+// Subscribe registers the push notification subscribers on the event bus.
+// Tapps still being coalesced are pushed as soon as the shutdown context is
+// done.
+func Subscribe(shutdown context.Context) {
+	event.Subscribe(func(ctx context.Context, e *event.TappCreated) {
+		coalesceTapp(ctx, e, tappCoalesceWindow(), shutdown.Done(), sendTapp)
+	})
+
+	event.Subscribe(func(ctx context.Context, e *event.TappSeen) {
+		if !env.TappSeenNotify.Value() {
+			return
+		}
+
+		SendIndividual(ctx, &TappNotification{
+			Title: fmt.Sprintf("Everyone in %s has seen your tapp!", e.Group.Name),
+			Body:  fmt.Sprintf("All members of the group %s have seen your tapp.", e.Group.Name),
+			Time:  e.Time,
+			Group: e.Group,
+			// The sender of the tapp is the receiver.
+			Account: e.Tapp.User,
+			Type:    "tapp_seen",
+		})
+	})
+
+	event.Subscribe(func(ctx context.Context, e *event.InviteSent) {
+		SendIndividual(ctx, &TappNotification{
+			Title: fmt.Sprintf("You have been invited to the group %s!", e.Group.Name),
+			Body: fmt.Sprintf(
+				"%s has invited you to join the group %s!", e.Inviter.Email, e.Group.Name,
+			),
+			Time:    e.Time,
+			Group:   e.Group,
+			Account: e.Invitee,
+		})
+	})
+
+	event.Subscribe(func(ctx context.Context, e *event.MemberJoined) {
+		SendMulticast(ctx, &TappNotification{
+			Title: fmt.Sprintf(
+				"%s has joined the group %s!", e.Account.UserIdentifier(), e.Group.Name,
+			),
+			Body: fmt.Sprintf(
+				"%s has accepted the invitation to join the group %s!",
+				e.Account.UserIdentifier(),
+				e.Group.Name,
+			),
+			Time:    e.Time,
+			Group:   e.Group,
+			Account: e.Account,
+		})
+	})
+
+	event.Subscribe(func(ctx context.Context, e *event.MemberLeft) {
+		SendMulticast(ctx, &TappNotification{
+			Title: fmt.Sprintf(
+				"%s has left the group %s!", e.Account.UserIdentifier(), e.Group.Name,
+			),
+			Body: fmt.Sprintf(
+				"%s has decided to leave the group %s!", e.Account.UserIdentifier(), e.Group.Name,
+			),
+			Time:    e.Time,
+			Group:   e.Group,
+			Account: e.Account,
+		})
+	})
+
+	event.Subscribe(func(ctx context.Context, e *event.MemberKicked) {
+		SendMulticast(ctx, &TappNotification{
+			Title: fmt.Sprintf(
+				"%s has been kicked from the group %s!", e.Account.UserIdentifier(), e.Group.Name,
+			),
+			Body: fmt.Sprintf(
+				"%s has been kicked from the group %s!", e.Account.UserIdentifier(), e.Group.Name,
+			),
+			Time:    e.Time,
+			Group:   e.Group,
+			Account: e.By,
+		})
+	})
+
+	event.Subscribe(func(ctx context.Context, e *event.MemberRoleChanged) {
+		change := "is now"
+		if e.Role != model.RoleAdmin {
+			change = "is no longer"
+		}
+
+		SendMulticast(ctx, &TappNotification{
+			Title: fmt.Sprintf(
+				"%s %s an admin of the group %s!", e.Account.UserIdentifier(), change, e.Group.Name,
+			),
+			Body: fmt.Sprintf(
+				"%s has changed the role of %s.", e.By.Email, e.Account.UserIdentifier(),
+			),
+			Time:    e.Time,
+			Group:   e.Group,
+			Account: e.By,
+		})
+	})
+
+	event.Subscribe(func(ctx context.Context, e *event.OwnershipOffered) {
+		SendIndividual(ctx, &TappNotification{
+			Title: fmt.Sprintf("You have been offered the ownership of %s!", e.Group.Name),
+			Body: fmt.Sprintf(
+				"%s wants you to take over the group %s.", e.From.UserIdentifier(), e.Group.Name,
+			),
+			Time:    e.Time,
+			Group:   e.Group,
+			Account: e.To,
+		})
+	})
+
+	event.Subscribe(func(ctx context.Context, e *event.OwnershipTransferred) {
+		SendMulticast(ctx, &TappNotification{
+			Title: fmt.Sprintf(
+				"%s is now the owner of the group %s!", e.To.UserIdentifier(), e.Group.Name,
+			),
+			Body: fmt.Sprintf(
+				"%s has handed the group %s over to %s.",
+				e.From.UserIdentifier(),
+				e.Group.Name,
+				e.To.UserIdentifier(),
+			),
+			Time:    e.Time,
+			Group:   e.Group,
+			Account: e.To,
+		})
+	})
+
+	event.Subscribe(func(ctx context.Context, e *event.GroupArchived) {
+		SendMulticast(ctx, &TappNotification{
+			Title:   fmt.Sprintf("The group %s has been archived!", e.Group.Name),
+			Body:    fmt.Sprintf("The group %s has been archived.", e.Group.Name),
+			Time:    e.Time,
+			Group:   e.Group,
+			Account: e.By,
+		})
+	})
+}
@@ -0,0 +1,126 @@
+package firebase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/zerologr"
+)
+
+// ErrUnregistered is reported by the fake notifier for the tokens it treats
+// as no longer registered.
+var ErrUnregistered = errors.New("token is not registered")
+
+// Add registers the FCM token of a session of the email. A token moves with
+// its device, so it is removed from any other session it was registered for.
+func Add(email, session, fcm string) {
+	zerologr.Info("adding email " + email + " to blob")
+	fcmLock.Lock()
+	defer fcmLock.Unlock()
+
+	removeTokens([]string{fcm})
+	if fcmBlob[email] == nil {
+		fcmBlob[email] = map[string]string{}
+	}
+	fcmBlob[email][sessionKey(session)] = fcm
+
+	writeBlob()
+	zerologr.Info("wrote to FCM blob", "email", email, "sessions", len(fcmBlob[email]))
+}
+
+// Remove unregisters the FCM token of a session of the email, the other
+// devices of the account are still notified.
+func Remove(email, session string) {
+	zerologr.Info("removing session of email " + email + " from blob")
+	fcmLock.Lock()
+	defer fcmLock.Unlock()
+
+	delete(fcmBlob[email], sessionKey(session))
+	if len(fcmBlob[email]) == 0 {
+		delete(fcmBlob, email)
+	}
+
+	writeBlob()
+	zerologr.Info("wrote to FCM blob", "email", email, "sessions", len(fcmBlob[email]))
+}
+
+// RemoveAccount unregisters all FCM tokens of the email.
+func RemoveAccount(email string) {
+	zerologr.Info("removing email " + email + " from blob")
+	fcmLock.Lock()
+	defer fcmLock.Unlock()
+
+	delete(fcmBlob, email)
+
+	writeBlob()
+	zerologr.Info("wrote to FCM blob", "accounts", len(fcmBlob))
+}
+
+// sessionKey returns the key of a session in the blob, a hash so that the
+// session tokens themselves are never written to disk.
+func sessionKey(session string) string {
+	sum := sha256.Sum256([]byte(session))
+	return hex.EncodeToString(sum[:])
+}
+
+// getFCM returns the FCM tokens of all sessions of the account.
+func getFCM(account *model.Account) []string {
+	fcmLock.Lock()
+	defer fcmLock.Unlock()
+
+	return slices.Sorted(maps.Values(fcmBlob[account.Email]))
+}
+
+// prune unregisters the tokens wherever they are registered.
+func prune(tokens []string) {
+	zerologr.Info("pruning invalid FCM tokens", "count", len(tokens))
+	fcmLock.Lock()
+	defer fcmLock.Unlock()
+
+	removeTokens(tokens)
+
+	writeBlob()
+}
+
+// removeTokens removes the tokens from the blob, the caller holds the lock.
+func removeTokens(tokens []string) {
+	for email, sessions := range fcmBlob {
+		maps.DeleteFunc(sessions, func(_, fcm string) bool {
+			return slices.Contains(tokens, fcm)
+		})
+		if len(sessions) == 0 {
+			delete(fcmBlob, email)
+		}
+	}
+}
+
+// invalidToken returns true if the error of a send means that the token will
+// never work again, because the app was uninstalled or the token belongs to
+// another sender. A malformed message is reported as an invalid argument too,
+// so those do not count.
+func invalidToken(err error) bool {
+	return messaging.IsUnregistered(err) || messaging.IsSenderIDMismatch(err) ||
+		errors.Is(err, ErrUnregistered)
+}
+
+// readLegacyBlob reads a blob mapping emails to a single token, as written
+// before accounts could have a token per session.
+func readLegacyBlob(data []byte) {
+	legacy := map[string]string{}
+	//nolint:govet,gosec
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		zerologr.Error(err, "failed to unmarshal FCM blob")
+		return
+	}
+
+	fcmBlob = map[string]map[string]string{}
+	for email, fcm := range legacy {
+		fcmBlob[email] = map[string]string{"": fcm}
+	}
+}
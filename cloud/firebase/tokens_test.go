@@ -0,0 +1,82 @@
+package firebase
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestTokens(t *testing.T) {
+	env.Parse()
+	defer func() { fcmBlob = map[string]map[string]string{} }()
+
+	alice := &model.Account{Email: "alice"}
+	Add("alice", "phone", "phone-token")
+	Add("alice", "tablet", "tablet-token")
+	if got := getFCM(alice); !slices.Equal(got, []string{"phone-token", "tablet-token"}) {
+		t.Errorf("got tokens %v, want both devices", got)
+	}
+
+	// Bob logs in on the tablet, it no longer notifies Alice.
+	Add("bob", "shared", "tablet-token")
+	if got := getFCM(alice); !slices.Equal(got, []string{"phone-token"}) {
+		t.Errorf("got tokens %v after the tablet moved", got)
+	}
+
+	Remove("alice", "phone")
+	if got := getFCM(alice); len(got) != 0 {
+		t.Errorf("got tokens %v after logging out", got)
+	}
+	if _, ok := fcmBlob["alice"]; ok {
+		t.Error("accounts without sessions should be removed")
+	}
+	if _, ok := fcmBlob["bob"][sessionKey("shared")]; !ok || fcmBlob["bob"]["shared"] != "" {
+		t.Error("sessions should be keyed by their hash")
+	}
+
+	Add("bob", "phone", "bob-token")
+	RemoveAccount("bob")
+	if got := getFCM(&model.Account{Email: "bob"}); len(got) != 0 {
+		t.Errorf("got tokens %v after removing the account", got)
+	}
+}
+
+func TestPruneInvalidTokens(t *testing.T) {
+	env.Parse()
+	defer db.Clear[*model.OutboxEntry](t.Context())
+	fake := NewFake()
+	fake.Unregistered = []string{"old-token"}
+	SetNotifier(fake)
+	defer SetNotifier(nil)
+
+	fcmBlob = map[string]map[string]string{
+		"alice": {"old": "old-token", "new": "new-token"},
+		"bob":   {"s1": "bob-token"},
+	}
+	defer func() { fcmBlob = map[string]map[string]string{} }()
+
+	group := &model.Group{ID: 1, Name: "Group 1", Owner: "bob", Members: []*model.Member{{Email: "alice"}}}
+	SendMulticast(t.Context(), &TappNotification{
+		Group: group, Account: &model.Account{Email: "bob"}, Exclude: []string{"bob"},
+	})
+	DrainOutbox(t.Context())
+
+	if got := getFCM(&model.Account{Email: "alice"}); !slices.Equal(got, []string{"new-token"}) {
+		t.Errorf("got tokens %v, want the unregistered token pruned", got)
+	}
+	if got := getFCM(&model.Account{Email: "bob"}); len(got) != 1 {
+		t.Errorf("got tokens %v, the tokens of others should be kept", got)
+	}
+}
+
+func TestReadLegacyBlob(t *testing.T) {
+	defer func() { fcmBlob = map[string]map[string]string{} }()
+
+	readLegacyBlob([]byte(`{"alice":"alice-token"}`))
+	if got := getFCM(&model.Account{Email: "alice"}); !slices.Equal(got, []string{"alice-token"}) {
+		t.Errorf("got tokens %v from the legacy blob", got)
+	}
+}
@@ -2,12 +2,13 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"regexp"
 
 	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/firebase"
 	"github.com/trebent/tapp-backend/model"
-	"github.com/trebent/zerologr"
 )
 
 var (
@@ -18,7 +19,7 @@ var (
 func handleAccountCreate(w http.ResponseWriter, r *http.Request) {
 	newAccount, err := model.Deserialize(r.Body, &model.Account{})
 	if err != nil {
-		zerologr.Error(err, "failed to deserialize account")
+		logger(r).Error(err, "failed to deserialize account")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonSerErr)
 		return
@@ -26,15 +27,15 @@ func handleAccountCreate(w http.ResponseWriter, r *http.Request) {
 
 	if !regexpEmail.MatchString(newAccount.Email) ||
 		!regexpPassword.MatchString(newAccount.Password) {
-		zerologr.Error(err, "account email or password format is bad")
+		logger(r).Error(err, "account email or password format is bad")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	existingAccounts, err := db.ReadAll[*model.Account]()
+	existingAccounts, err := db.ReadAll[*model.Account](r.Context())
 	if err != nil {
-		zerologr.Error(err, "failed to read all accounts from DB")
+		logger(r).Error(err, "failed to read all accounts from DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
@@ -42,31 +43,36 @@ func handleAccountCreate(w http.ResponseWriter, r *http.Request) {
 
 	for _, existingAccount := range existingAccounts {
 		if existingAccount.Key() == newAccount.Key() {
-			zerologr.Error(err, "account with that email already exists")
+			logger(r).Error(err, "account with that email already exists")
 			w.WriteHeader(http.StatusConflict)
 			return
 		}
 
 		if newAccount.Tag != "" && existingAccount.Tag == newAccount.Tag {
-			zerologr.Error(err, "account with that tag already exists")
+			logger(r).Error(err, "account with that tag already exists")
 			w.WriteHeader(http.StatusConflict)
 			return
 		}
 	}
 
 	//nolint:gosec,govet
-	if err := db.Save(newAccount); err != nil {
-		zerologr.Error(err, "save account to DB failed")
+	if err := db.Save(r.Context(), newAccount); err != nil {
+		logger(r).Error(err, "save account to DB failed")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
 
+	//nolint:gosec,govet
+	if err := indexTag(r.Context(), "", newAccount); err != nil {
+		logger(r).Error(err, "failed to index account tag")
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	newAccount.Password = ""
 	//nolint:gosec,govet
 	if err := model.WriteJSON(w, newAccount); err != nil {
-		zerologr.Error(err, "failed to serialize account")
+		logger(r).Error(err, "failed to serialize account")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonSerErr)
 		return
@@ -76,15 +82,15 @@ func handleAccountCreate(w http.ResponseWriter, r *http.Request) {
 func handleAccountGet(w http.ResponseWriter, r *http.Request) {
 	email := r.URL.Path[len("/accounts/"):]
 
-	account, err := db.Read(&model.Account{Email: email})
+	account, err := db.Read(r.Context(), &model.Account{Email: email})
 	if err != nil {
-		zerologr.Error(err, "account not found")
+		logger(r).Error(err, "account not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	if account.Email != getUserEmailFromToken(r) {
-		zerologr.Error(err, "that's not that user's account")
+		logger(r).Error(err, "that's not that user's account")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
@@ -92,7 +98,7 @@ func handleAccountGet(w http.ResponseWriter, r *http.Request) {
 	account.Password = ""
 	//nolint:gosec,govet
 	if err := model.WriteJSON(w, account); err != nil {
-		zerologr.Error(err, "failed to serialize account")
+		logger(r).Error(err, "failed to serialize account")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -101,16 +107,16 @@ func handleAccountGet(w http.ResponseWriter, r *http.Request) {
 func handleAccountUpdate(w http.ResponseWriter, r *http.Request) {
 	email := r.URL.Path[len("/accounts/"):]
 
-	existingAccount, err := db.Read(&model.Account{Email: email})
+	existingAccount, err := db.Read(r.Context(), &model.Account{Email: email})
 	if err != nil {
-		zerologr.Error(err, "account not found")
+		logger(r).Error(err, "account not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	updatedAccount, err := model.Deserialize(r.Body, &model.Account{})
 	if err != nil {
-		zerologr.Error(err, "failed to deserialize account")
+		logger(r).Error(err, "failed to deserialize account")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonSerErr)
 		return
@@ -118,42 +124,47 @@ func handleAccountUpdate(w http.ResponseWriter, r *http.Request) {
 	updatedAccount.Password = existingAccount.Password
 
 	if existingAccount.Email != updatedAccount.Email {
-		zerologr.Error(err, "user tried to update email")
+		logger(r).Error(err, "user tried to update email")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
 	//nolint:ineffassign
-	accounts, err := db.ReadAll[*model.Account]()
+	accounts, err := db.ReadAll[*model.Account](r.Context())
 	if err != nil {
-		zerologr.Error(err, "failed to read all accounts")
+		logger(r).Error(err, "failed to read all accounts")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
 
 	for _, a := range accounts {
-		if updatedAccount.Tag != "" && a.Tag == updatedAccount.Tag {
-			zerologr.Error(err, "that tag already exists")
+		if updatedAccount.Tag != "" && a.Tag == updatedAccount.Tag && a.Email != email {
+			logger(r).Error(err, "that tag already exists")
 			w.WriteHeader(http.StatusConflict)
 			return
 		}
 	}
 
 	//nolint:gosec,govet
-	if err := db.Save(updatedAccount); err != nil {
-		zerologr.Error(err, "failed to save updated account to DB")
+	if err := db.Save(r.Context(), updatedAccount); err != nil {
+		logger(r).Error(err, "failed to save updated account to DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
 
+	//nolint:gosec,govet
+	if err := indexTag(r.Context(), existingAccount.Tag, updatedAccount); err != nil {
+		logger(r).Error(err, "failed to index account tag")
+	}
+
 	updatedAccount.Password = ""
 
 	//nolint:gosec,govet
 	if err := model.WriteJSON(w, updatedAccount); err != nil {
-		zerologr.Error(err, "failed to serialize account")
+		logger(r).Error(err, "failed to serialize account")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonSerErr)
 		return
@@ -163,22 +174,22 @@ func handleAccountUpdate(w http.ResponseWriter, r *http.Request) {
 func handlePasswordUpdate(w http.ResponseWriter, r *http.Request) {
 	email := getUserEmailFromToken(r)
 
-	existingAccount, err := db.Read(&model.Account{Email: email})
+	existingAccount, err := db.Read(r.Context(), &model.Account{Email: email})
 	if err != nil {
-		zerologr.Error(err, "account not found")
+		logger(r).Error(err, "account not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	acc := &model.Account{}
 	if _, err := model.Deserialize(r.Body, &acc); err != nil {
-		zerologr.Error(err, "failed to unmarshal password body")
+		logger(r).Error(err, "failed to unmarshal password body")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonSerErr)
 	}
 
 	if !regexpPassword.MatchString(acc.Password) {
-		zerologr.Error(err, "password format incorrect")
+		logger(r).Error(err, "password format incorrect")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
@@ -187,8 +198,8 @@ func handlePasswordUpdate(w http.ResponseWriter, r *http.Request) {
 	existingAccount.Password = acc.Password
 
 	//nolint:gosec,govet
-	if err := db.Save(existingAccount); err != nil {
-		zerologr.Error(err, "failed to save updated account to DB")
+	if err := db.Save(r.Context(), existingAccount); err != nil {
+		logger(r).Error(err, "failed to save updated account to DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
@@ -200,20 +211,45 @@ func handlePasswordUpdate(w http.ResponseWriter, r *http.Request) {
 func handleAccountDelete(w http.ResponseWriter, r *http.Request) {
 	email := r.URL.Path[len("/accounts/"):]
 
-	existingAccount, err := db.Read(&model.Account{Email: email})
+	existingAccount, err := db.Read(r.Context(), &model.Account{Email: email})
 	if err != nil {
-		zerologr.Error(err, "failed to find account in DB")
+		logger(r).Error(err, "failed to find account in DB")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
+	//nolint:gosec,govet
+	if err := departGroups(r, existingAccount.Email); err != nil {
+		logger(r).Error(err, "failed to remove account from its groups")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 	//nolint:gosec,govet
-	if err := db.Delete(existingAccount); err != nil {
-		zerologr.Error(err, "failed to delete account from DB")
+	if err := db.Delete(r.Context(), existingAccount); err != nil {
+		logger(r).Error(err, "failed to delete account from DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
+
+	//nolint:gosec,govet
+	if err := indexTag(r.Context(), existingAccount.Tag, &model.Account{}); err != nil {
+		logger(r).Error(err, "failed to remove account tag from index")
+	}
+	deleteLocked(r.Context(), &model.Blocklist{Email: existingAccount.Email})
+	deleteLocked(r.Context(), &model.Preferences{Email: existingAccount.Email})
+	deleteLocked(r.Context(), &model.Digest{Email: existingAccount.Email})
+	firebase.RemoveAccount(existingAccount.Email)
 	handleLogout(w, r)
 }
+
+// deleteLocked deletes the entity, if there is one, under the lock of its table.
+func deleteLocked[T db.Model](ctx context.Context, entity T) {
+	db.AquireTableLock[T](ctx)
+	defer db.ReleaseTableLock[T]()
+
+	_ = db.Delete(ctx, entity)
+}
@@ -11,7 +11,8 @@ import (
 )
 
 func TestAccountCreate(t *testing.T) {
-	defer db.Clear[*model.Account]()
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.AccountTag](t.Context())
 	env.Parse()
 
 	tests := []tc{
@@ -60,10 +61,10 @@ func TestAccountCreate(t *testing.T) {
 }
 
 func TestAccountGet(t *testing.T) {
-	defer db.Clear[*model.Account]()
+	defer db.Clear[*model.Account](t.Context())
 	env.Parse()
 
-	db.Save(&model.Account{
+	db.Save(t.Context(), &model.Account{
 		Tag:      "tag",
 		Email:    "email@domain.se",
 		Password: "password",
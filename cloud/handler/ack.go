@@ -0,0 +1,141 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+)
+
+// handleTappAck acknowledges a tapp for the user, the state query parameter is
+// delivered or seen, seen unless set. Acks never go back from seen to
+// delivered, and repeated acks are ignored.
+func handleTappAck(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	i, err := strconv.Atoi(parts[2])
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	tappID, err := strconv.Atoi(parts[4])
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	state := model.AckState(r.URL.Query().Get("state"))
+	if state == "" {
+		state = model.AckSeen
+	}
+	if !state.Valid() {
+		logger(r).Info("unknown ack state", "state", state)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	group, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if !group.IsMember(email) {
+		logger(r).Error(err, "user is not a member of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	tapps, err := db.SimpleRead(r.Context(), &model.Tapp{GroupID: group.ID})
+	if err != nil {
+		logger(r).Error(err, "failed to read all tapps from DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+	model.NumberTapps(tapps)
+
+	if tappID < 1 || tappID > len(tapps) {
+		logger(r).Error(err, "tapp not found", "tapp", tappID)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	tapp := tapps[tappID-1]
+	if tapp.User.Email == email {
+		logger(r).Info("user can not acknowledge their own tapp")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	newAck := &model.TappAck{GroupID: group.ID, TappID: tappID, Email: email, State: state}
+	db.SimpleAcquire(r.Context(), newAck)
+	defer db.SimpleRelease(newAck)
+
+	acks, err := db.SimpleRead(r.Context(), newAck)
+	if err != nil {
+		logger(r).Error(err, "failed to read all tapp acks from DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	previous := model.SummarizeAcks(acks)[tappID].StateOf(email)
+	if previous == state || previous == model.AckSeen {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if account, err := db.Read(r.Context(), &model.Account{Email: email}); err == nil {
+		newAck.Tag = account.Tag
+	}
+	newAck.Time = time.Now().UnixMilli()
+
+	//nolint:gosec,govet
+	if err := db.SimpleAppend(r.Context(), newAck); err != nil {
+		logger(r).Error(err, "failed to save tapp ack to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	summary := model.SummarizeAcks(append(acks, newAck))[tappID]
+	if state == model.AckSeen && summary.SeenByAll(group, tapp.User.Email) {
+		tapp.Acks = summary
+		event.Publish(r.Context(), &event.TappSeen{Time: newAck.Time, Group: group, Tapp: tapp})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// attachAcks fills in the acknowledgements of the tapps of the group.
+func attachAcks(ctx context.Context, groupID int, tapps []*model.Tapp) error {
+	acks, err := db.SimpleRead(ctx, &model.TappAck{GroupID: groupID})
+	if err != nil {
+		return err
+	}
+
+	summaries := model.SummarizeAcks(acks)
+	for _, tapp := range tapps {
+		tapp.Acks = summaries[tapp.ID]
+		if tapp.Acks == nil {
+			tapp.Acks = &model.TappAcks{Members: []*model.TappAck{}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestTappAck(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	defer db.SimpleClear(t.Context(), &model.Tapp{GroupID: 1})
+	defer db.SimpleClear(t.Context(), &model.TappAck{GroupID: 1})
+	env.Parse()
+
+	tokens := map[string]string{
+		"alice@domain.se": loginAs(t, "alice@domain.se"),
+		"bob@domain.se":   loginAs(t, "bob@domain.se"),
+	}
+	saveGroup(t, "alice@domain.se", "bob@domain.se")
+
+	tapp := &model.Tapp{ID: 1, Time: 1000, GroupID: 1, User: &model.Account{Email: "alice@domain.se"}}
+	if err := db.SimpleAppend(t.Context(), tapp); err != nil {
+		t.Fatalf("failed to create tapp: %v", err)
+	}
+
+	tests := []tc{
+		{name: "Delivered", url: "/groups/1/tapp/1/ack?state=delivered", token: tokens["bob@domain.se"], wantStatus: 204},
+		{name: "Seen", url: "/groups/1/tapp/1/ack", token: tokens["bob@domain.se"], wantStatus: 204},
+		{name: "Back to delivered", url: "/groups/1/tapp/1/ack?state=delivered", token: tokens["bob@domain.se"], wantStatus: 204},
+		{name: "Unknown state", url: "/groups/1/tapp/1/ack?state=read", token: tokens["bob@domain.se"], wantStatus: 400},
+		{name: "Own tapp", url: "/groups/1/tapp/1/ack", token: tokens["alice@domain.se"], wantStatus: 400},
+		{name: "Unknown tapp", url: "/groups/1/tapp/2/ack", token: tokens["bob@domain.se"], wantStatus: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.url, nil)
+			req.Header.Set("Authorization", tt.token)
+			recorder := httptest.NewRecorder()
+
+			handleTappAck(recorder, req)
+
+			if recorder.Result().StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/groups/1/tapp", nil)
+	req.Header.Set("Authorization", tokens["alice@domain.se"])
+	recorder := httptest.NewRecorder()
+	handleTappGet(recorder, req)
+
+	tapps := []*model.Tapp{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &tapps); err != nil {
+		t.Fatalf("failed to unmarshal tapps: %v", err)
+	}
+	if len(tapps) != 1 || tapps[0].Acks == nil || tapps[0].Acks.Seen != 1 ||
+		tapps[0].Acks.Delivered != 1 || len(tapps[0].Acks.Members) != 1 {
+		t.Errorf("unexpected tapp acks: %+v", tapps)
+	}
+}
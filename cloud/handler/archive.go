@@ -0,0 +1,256 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/tapp-backend/tracing"
+)
+
+const purgeInterval = time.Hour
+
+var jsonArchivedErr = []byte(`{"error": "group is archived"}`)
+
+func restoreWindow() time.Duration {
+	return time.Duration(env.GroupRestoreHours.Value()) * time.Hour
+}
+
+// groupArchived writes a 410 and returns true if the group in the path is
+// archived, archived groups are read-only. Groups that can not be read are left
+// for the handler to report.
+func groupArchived(w http.ResponseWriter, r *http.Request) bool {
+	i, err := strconv.Atoi(strings.Split(r.URL.Path, "/")[2])
+	if err != nil {
+		return false
+	}
+
+	group, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil || group.Archived == 0 {
+		return false
+	}
+
+	logger(r).Info("rejecting change to archived group", "group", group.ID)
+	w.WriteHeader(http.StatusGone)
+	w.Write(jsonArchivedErr)
+	return true
+}
+
+// archiveWrapper makes the group route read-only while the group is archived,
+// rejecting every method but GET. Unauthenticated requests are passed on so
+// that the route answers them, and never learn whether a group is archived.
+func archiveWrapper(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && getUserEmailFromToken(r) != "" && groupArchived(w, r) {
+			return
+		}
+		h(w, r)
+	}
+}
+
+// handleGroupRestore brings an archived group back, as long as it is within the
+// restore window.
+func handleGroupRestore(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if existingGroup.Owner != email {
+		logger(r).Error(err, "user is not the owner of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if existingGroup.Archived == 0 {
+		logger(r).Error(err, "group is not archived")
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	archived := time.UnixMilli(existingGroup.Archived)
+	if time.Since(archived) >= restoreWindow() {
+		logger(r).Error(err, "restore window of the group has passed")
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	atLimit, err := ownsMaxGroups(r.Context(), email)
+	if err != nil {
+		logger(r).Error(err, "failed to count owned groups")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+	if atLimit {
+		logger(r).Error(err, "user has reached the owned group limit")
+		w.WriteHeader(http.StatusConflict)
+		w.Write(jsonGroupLimitErr)
+		return
+	}
+
+	existingGroup.Archived = 0
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, existingGroup); err != nil {
+		logger(r).Error(err, "failed to write group to response body")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+// PurgeGroups deletes archived groups for good once their restore window has
+// passed, until the context is done.
+func PurgeGroups(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			//nolint:gosec,govet
+			if err := purgeGroups(ctx); err != nil {
+				tracing.Logger(ctx).Error(err, "failed to purge archived groups")
+			}
+		}
+	}
+}
+
+// purgeGroups deletes the archived groups past their restore window along with
+// their invitations, invite codes, schedules, tapp history, tapp acks and
+// statistics.
+func purgeGroups(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "purge groups")
+	defer span.End()
+
+	db.AquireTableLock[*model.Group](ctx)
+	defer db.ReleaseTableLock[*model.Group]()
+
+	groups, err := db.ReadAll[*model.Group](ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	purged := 0
+	for _, group := range groups {
+		if group.Archived == 0 || now.Sub(time.UnixMilli(group.Archived)) < restoreWindow() {
+			continue
+		}
+
+		if err := purgeGroup(ctx, group); err != nil {
+			return err
+		}
+		purged++
+
+		event.Publish(ctx, &event.GroupDeleted{Time: now.UnixMilli(), Group: group})
+	}
+
+	tracing.Logger(ctx).Info("purged archived groups", "purged", purged)
+	return nil
+}
+
+func purgeGroup(ctx context.Context, group *model.Group) error {
+	db.AquireTableLock[*model.Invitation](ctx)
+	defer db.ReleaseTableLock[*model.Invitation]()
+
+	invites, err := db.ReadAll[*model.Invitation](ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, invite := range invites {
+		if invite.GroupID == group.ID {
+			_ = db.Delete(ctx, invite)
+		}
+	}
+
+	db.AquireTableLock[*model.InviteCode](ctx)
+	defer db.ReleaseTableLock[*model.InviteCode]()
+
+	codes, err := db.ReadAll[*model.InviteCode](ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		if code.GroupID == group.ID {
+			_ = db.Delete(ctx, code)
+		}
+	}
+
+	// Tapps and acks are appended under their own locks, not the group one.
+	tapps := &model.Tapp{GroupID: group.ID}
+	db.SimpleAcquire(ctx, tapps)
+	err = db.SimpleClear(ctx, tapps)
+	db.SimpleRelease(tapps)
+	if err != nil {
+		return err
+	}
+
+	acks := &model.TappAck{GroupID: group.ID}
+	db.SimpleAcquire(ctx, acks)
+	err = db.SimpleClear(ctx, acks)
+	db.SimpleRelease(acks)
+	if err != nil {
+		return err
+	}
+
+	db.AquireTableLock[*model.Schedule](ctx)
+	defer db.ReleaseTableLock[*model.Schedule]()
+
+	schedules, err := db.ReadAll[*model.Schedule](ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		if schedule.GroupID == group.ID {
+			_ = db.Delete(ctx, schedule)
+		}
+	}
+
+	db.AquireTableLock[*model.GroupStats](ctx)
+	defer db.ReleaseTableLock[*model.GroupStats]()
+
+	_ = db.Delete(ctx, &model.GroupStats{GroupID: group.ID})
+
+	if group.Avatar != "" {
+		deleteAvatar(ctx, group.ID)
+	}
+	return db.Delete(ctx, group)
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestPurgeGroups(t *testing.T) {
+	defer db.Clear[*model.Group](t.Context())
+	defer db.Clear[*model.Invitation](t.Context())
+	env.Parse()
+
+	expired := time.Now().Add(-restoreWindow() - time.Minute).UnixMilli()
+	groups := []*model.Group{
+		{ID: 1, Name: "Active", Owner: "owner@domain.se"},
+		{ID: 2, Name: "Restorable", Owner: "owner@domain.se", Archived: time.Now().UnixMilli()},
+		{ID: 3, Name: "Expired", Owner: "owner@domain.se", Archived: expired},
+	}
+	for _, group := range groups {
+		if err := db.Save(t.Context(), group); err != nil {
+			t.Fatalf("failed to create group: %v", err)
+		}
+	}
+	if err := db.Save(t.Context(), &model.Invitation{GroupID: 3, Email: "email@domain.se"}); err != nil {
+		t.Fatalf("failed to create invitation: %v", err)
+	}
+
+	if err := purgeGroups(t.Context()); err != nil {
+		t.Fatalf("failed to purge groups: %v", err)
+	}
+
+	if !db.Exists(t.Context(), groups[0]) || !db.Exists(t.Context(), groups[1]) {
+		t.Error("expected active and restorable groups to be kept")
+	}
+	if db.Exists(t.Context(), groups[2]) {
+		t.Error("expected the expired group to be purged")
+	}
+	if db.Exists(t.Context(), &model.Invitation{GroupID: 3, Email: "email@domain.se"}) {
+		t.Error("expected the invitations of the purged group to be deleted")
+	}
+}
+
+func TestGroupDeleteRestore(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	env.Parse()
+
+	token := loginAs(t, "email@domain.se")
+	saveGroup(t, "email@domain.se")
+
+	req := httptest.NewRequest("DELETE", "/groups/1", nil)
+	req.Header.Set("Authorization", token)
+	recorder := httptest.NewRecorder()
+	handleGroupDelete(recorder, req)
+	if recorder.Code != 204 {
+		t.Fatalf("got status %d deleting the group, want %d", recorder.Code, 204)
+	}
+
+	for _, route := range []struct{ method, url string }{
+		{"PUT", "/groups/1"}, {"POST", "/groups/1/tapp"}, {"POST", "/groups/1/leave"},
+	} {
+		req = httptest.NewRequest(route.method, route.url, nil)
+		req.Header.Set("Authorization", token)
+		recorder = httptest.NewRecorder()
+		Handler().ServeHTTP(recorder, req)
+		if recorder.Code != 410 {
+			t.Errorf("expected %s %s to the archived group to be rejected, got %d",
+				route.method, route.url, recorder.Code)
+		}
+	}
+
+	req = httptest.NewRequest("PUT", "/groups/1", nil)
+	recorder = httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, req)
+	if recorder.Code != 401 {
+		t.Errorf("got status %d for an unauthenticated change, want %d", recorder.Code, 401)
+	}
+
+	req = httptest.NewRequest("POST", "/groups/1/restore", nil)
+	req.Header.Set("Authorization", token)
+	recorder = httptest.NewRecorder()
+	handleGroupRestore(recorder, req)
+	if recorder.Code != 200 {
+		t.Fatalf("got status %d restoring the group, want %d", recorder.Code, 200)
+	}
+
+	group, err := db.Read(t.Context(), &model.Group{ID: 1})
+	if err != nil || group.Archived != 0 {
+		t.Errorf("expected the group to be restored, got %+v", group)
+	}
+}
@@ -2,6 +2,7 @@
 package handler
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
@@ -10,9 +11,12 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/trebent/tapp-backend/blob"
 	"github.com/trebent/tapp-backend/db"
 	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/event"
 	"github.com/trebent/tapp-backend/firebase"
+	"github.com/trebent/tapp-backend/metrics"
 	"github.com/trebent/tapp-backend/model"
 	"github.com/trebent/zerologr"
 )
@@ -30,6 +34,17 @@ func Initialize() {
 	defer authLock.Unlock()
 	readAuthBlob()
 	zerologr.Info("booted with auth blob", "blob", authBlob)
+	metrics.SetActiveSessions(len(authBlob))
+
+	blob.Set(blob.NewFileStore(filepath.Join(env.FileSystem.Value(), "blobs")))
+
+	//nolint:gosec,govet
+	if err := rebuildTagIndex(context.Background()); err != nil {
+		zerologr.Error(err, "failed to rebuild the tag index")
+	}
+
+	event.SubscribeAll(publishToStreams)
+	event.Subscribe(recordTappStats)
 }
 
 func authenticated(w http.ResponseWriter, r *http.Request) bool {
@@ -63,13 +78,13 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 	}
 	if _, err := model.Deserialize(r.Body, &body); err != nil {
-		zerologr.Error(err, "failed to deserialize login request")
+		logger(r).Error(err, "failed to deserialize login request")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonSerErr)
 		return
 	}
 
-	account, err := db.Read(&model.Account{Email: body.Email})
+	account, err := db.Read(r.Context(), &model.Account{Email: body.Email})
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
@@ -86,6 +101,7 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 	defer authLock.Unlock()
 	authBlob[hash] = body.Email
 	writeAuthBlob()
+	metrics.SetActiveSessions(len(authBlob))
 
 	w.Header().Set("Authorization", hash)
 	w.WriteHeader(http.StatusNoContent)
@@ -95,13 +111,14 @@ func handleLogout(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 
 	email := getUserEmailFromToken(r)
-	firebase.Remove(email)
-
 	token := r.Header.Get("Authorization")
+	firebase.Remove(email, token)
+
 	authLock.Lock()
 	defer authLock.Unlock()
 	delete(authBlob, token)
 	writeAuthBlob()
+	metrics.SetActiveSessions(len(authBlob))
 }
 
 func writeAuthBlob() {
@@ -11,10 +11,10 @@ import (
 )
 
 func TestLoginGetAccount(t *testing.T) {
-	defer db.Clear[*model.Account]()
+	defer db.Clear[*model.Account](t.Context())
 	env.Parse()
 
-	db.Save(&model.Account{
+	db.Save(t.Context(), &model.Account{
 		Tag:      "tag",
 		Email:    "email@domain.se",
 		Password: "password",
@@ -42,10 +42,10 @@ func TestLoginGetAccount(t *testing.T) {
 }
 
 func TestLoginLogout(t *testing.T) {
-	defer db.Clear[*model.Account]()
+	defer db.Clear[*model.Account](t.Context())
 	env.Parse()
 
-	db.Save(&model.Account{
+	db.Save(t.Context(), &model.Account{
 		Tag:      "tag",
 		Email:    "email@domain.se",
 		Password: "password",
@@ -0,0 +1,304 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Register the GIF decoder.
+	"image/jpeg"
+	_ "image/png" // Register the PNG decoder.
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trebent/tapp-backend/blob"
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/model"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register the WebP decoder.
+)
+
+const (
+	avatarMaxBytes     = 5 << 20
+	avatarMaxDimension = 4096
+	avatarQuality      = 85
+	avatarMaxAge       = 7 * 24 * time.Hour
+)
+
+// avatarSizes are the square thumbnail sizes, in pixels, an uploaded avatar is
+// resized to.
+//
+//nolint:gochecknoglobals
+var avatarSizes = map[string]int{
+	"small": 128,
+	"large": 512,
+}
+
+//nolint:gochecknoglobals
+var avatarTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+func avatarKey(groupID int, size string) string {
+	return fmt.Sprintf("avatars/%d/%s.jpg", groupID, size)
+}
+
+// encodeAvatar crops the image to a centered square, scales it down to the size
+// and encodes it as a JPEG. Transparent areas become white.
+func encodeAvatar(img image.Image, size int) ([]byte, error) {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Point{
+		X: bounds.Min.X + (bounds.Dx()-side)/2,
+		Y: bounds.Min.Y + (bounds.Dy()-side)/2,
+	})
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Over, nil)
+
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, dst, &jpeg.Options{Quality: avatarQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// deleteAvatar removes all thumbnails of the group avatar, if any.
+func deleteAvatar(ctx context.Context, groupID int) {
+	for size := range avatarSizes {
+		_ = blob.Delete(ctx, avatarKey(groupID, size))
+	}
+}
+
+// avatarEditable returns http.StatusOK if the user may change the avatar of
+// the group, or the status to answer with otherwise.
+func avatarEditable(r *http.Request, groupID int) int {
+	group, err := db.Read(r.Context(), &model.Group{ID: groupID})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		return http.StatusNotFound
+	}
+	if !group.Can(getUserEmailFromToken(r), model.PermissionEdit) {
+		logger(r).Error(err, "user is not allowed to edit the group")
+		return http.StatusForbidden
+	}
+	return http.StatusOK
+}
+
+// handleGroupAvatarUpload takes a raw JPEG, PNG, GIF or WebP image as the
+// request body and stores it as the group avatar.
+func handleGroupAvatarUpload(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	// Check the permission before reading and decoding the image, so that only
+	// editors of the group can make the server do that work. It is checked again
+	// under the lock before saving.
+	if status := avatarEditable(r, i); status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, avatarMaxBytes))
+	if err != nil {
+		logger(r).Error(err, "failed to read avatar")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !slices.Contains(avatarTypes, http.DetectContentType(data)) {
+		logger(r).Info("unsupported avatar type", "type", http.DetectContentType(data))
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Check the dimensions before decoding, so that a small file can not make
+	// the server allocate a huge image.
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width > avatarMaxDimension || config.Height > avatarMaxDimension ||
+		config.Width == 0 || config.Height == 0 {
+		logger(r).Error(err, "avatar dimensions are invalid")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		logger(r).Error(err, "failed to decode avatar")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !existingGroup.Can(getUserEmailFromToken(r), model.PermissionEdit) {
+		logger(r).Error(err, "user is not allowed to edit the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	for size, pixels := range avatarSizes {
+		encoded, err := encodeAvatar(img, pixels)
+		if err != nil {
+			logger(r).Error(err, "failed to encode avatar")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		//nolint:gosec,govet
+		if err := blob.Put(r.Context(), avatarKey(existingGroup.ID, size), encoded); err != nil {
+			logger(r).Error(err, "failed to store avatar")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The version in the reference changes with every upload, so that clients
+	// can cache avatars for long.
+	existingGroup.Avatar = fmt.Sprintf(
+		"/groups/%d/avatar?v=%d", existingGroup.ID, time.Now().UnixMilli(),
+	)
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, existingGroup); err != nil {
+		logger(r).Error(err, "failed to write group to response body")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+// handleGroupAvatarGet serves the group avatar in the size given by the size
+// query parameter, small unless set.
+func handleGroupAvatarGet(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = "small"
+	}
+	if _, ok := avatarSizes[size]; !ok {
+		logger(r).Info("unknown avatar size", "size", size)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	group, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !group.IsMember(getUserEmailFromToken(r)) {
+		logger(r).Error(err, "user is not a member of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if group.Avatar == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	etag := strconv.Quote(group.Avatar[strings.LastIndex(group.Avatar, "=")+1:] + "-" + size)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(avatarMaxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, err := blob.Get(r.Context(), avatarKey(group.ID, size))
+	if err != nil {
+		logger(r).Error(err, "failed to read avatar")
+		w.Header().Del("ETag")
+		w.Header().Del("Cache-Control")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+func handleGroupAvatarDelete(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !existingGroup.Can(getUserEmailFromToken(r), model.PermissionEdit) {
+		logger(r).Error(err, "user is not allowed to edit the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	deleteAvatar(r.Context(), existingGroup.ID)
+	existingGroup.Avatar = ""
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/trebent/tapp-backend/blob"
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestGroupAvatar(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	env.Parse()
+	blob.Set(blob.NewFileStore(t.TempDir()))
+
+	token := loginAs(t, "email@domain.se")
+	saveGroup(t, "email@domain.se")
+	if err := db.Save(t.Context(), &model.Group{ID: 2, Name: "Group 2", Owner: "other@domain.se"}); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+
+	img := &bytes.Buffer{}
+	if err := png.Encode(img, image.NewRGBA(image.Rect(0, 0, 300, 200))); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+
+	// Strangers are turned away before the body is even looked at.
+	req := httptest.NewRequest("PUT", "/groups/2/avatar", strings.NewReader("not an image"))
+	req.Header.Set("Authorization", token)
+	recorder := httptest.NewRecorder()
+	handleGroupAvatarUpload(recorder, req)
+	if recorder.Code != 403 {
+		t.Errorf("got status %d uploading to another group, want %d", recorder.Code, 403)
+	}
+
+	req = httptest.NewRequest("PUT", "/groups/1/avatar", strings.NewReader("not an image"))
+	req.Header.Set("Authorization", token)
+	recorder = httptest.NewRecorder()
+	handleGroupAvatarUpload(recorder, req)
+	if recorder.Code != 415 {
+		t.Errorf("got status %d uploading text, want %d", recorder.Code, 415)
+	}
+
+	req = httptest.NewRequest("PUT", "/groups/1/avatar", img)
+	req.Header.Set("Authorization", token)
+	recorder = httptest.NewRecorder()
+	handleGroupAvatarUpload(recorder, req)
+	if recorder.Code != 200 {
+		t.Fatalf("got status %d uploading the avatar, want %d", recorder.Code, 200)
+	}
+
+	req = httptest.NewRequest("GET", "/groups/1/avatar?size=large", nil)
+	req.Header.Set("Authorization", token)
+	recorder = httptest.NewRecorder()
+	handleGroupAvatarGet(recorder, req)
+	if recorder.Code != 200 || recorder.Header().Get("ETag") == "" {
+		t.Fatalf("got status %d serving the avatar, want %d", recorder.Code, 200)
+	}
+
+	served, _, err := image.DecodeConfig(recorder.Body)
+	if err != nil || served.Width != 512 || served.Height != 512 {
+		t.Errorf("got a %dx%d avatar and error %v", served.Width, served.Height, err)
+	}
+
+	etag := recorder.Header().Get("ETag")
+	req = httptest.NewRequest("GET", "/groups/1/avatar?size=large", nil)
+	req.Header.Set("Authorization", token)
+	req.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	handleGroupAvatarGet(recorder, req)
+	if recorder.Code != 304 {
+		t.Errorf("got status %d for a cached avatar, want %d", recorder.Code, 304)
+	}
+}
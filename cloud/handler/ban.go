@@ -0,0 +1,191 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+)
+
+// handleGroupBan bans an account from the group, removing it from the group
+// first if it is a member or has been invited.
+func handleGroupBan(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if !existingGroup.Can(email, model.PermissionKick) {
+		logger(r).Error(err, "user is not allowed to ban from the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	bannedEmail, err := targetEmail(r)
+	if errors.Is(err, errNoTarget) {
+		logger(r).Error(err, "no email or tag to ban found in query parameters")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+	if err != nil {
+		logger(r).Error(err, "no account found matching the banned tag")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	wasMember := existingGroup.IsMember(bannedEmail)
+	if wasMember && !existingGroup.Outranks(email, bannedEmail) {
+		logger(r).Error(err, "user can only ban members of a lower role")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	bannedAccount, err := db.Read(r.Context(), &model.Account{Email: bannedEmail})
+	if err != nil {
+		logger(r).Error(err, "no email found matching the banned email")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	existingGroup.Ban(bannedAccount, email, now)
+
+	db.AquireTableLock[*model.Invitation](r.Context())
+	defer db.ReleaseTableLock[*model.Invitation]()
+
+	_ = db.Delete(r.Context(), &model.Invitation{GroupID: existingGroup.ID, Email: bannedEmail})
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	if wasMember {
+		cancelSchedules(r.Context(), existingGroup.ID, bannedEmail)
+
+		event.Publish(r.Context(), &event.MemberKicked{
+			Time:    now,
+			Group:   existingGroup,
+			Account: &model.Account{Email: bannedAccount.Email, Tag: bannedAccount.Tag},
+			By:      &model.Account{Email: email},
+		})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleGroupBanList(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !existingGroup.Can(getUserEmailFromToken(r), model.PermissionKick) {
+		logger(r).Error(err, "user is not allowed to see the bans of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	bans := existingGroup.Bans
+	if bans == nil {
+		bans = []*model.Ban{}
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, bans); err != nil {
+		logger(r).Error(err, "failed to serialize bans")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+func handleGroupUnban(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !existingGroup.Can(getUserEmailFromToken(r), model.PermissionKick) {
+		logger(r).Error(err, "user is not allowed to lift bans in the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	bannedEmail, err := targetEmail(r)
+	if errors.Is(err, errNoTarget) {
+		logger(r).Error(err, "no email or tag to unban found in query parameters")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+	if err != nil || !existingGroup.Unban(bannedEmail) {
+		logger(r).Error(err, "no ban found matching the email or tag")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
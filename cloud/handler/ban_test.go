@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestGroupInviteBansAndBlocks(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	defer db.Clear[*model.Invitation](t.Context())
+	defer db.Clear[*model.Blocklist](t.Context())
+	env.Parse()
+
+	token := loginAs(t, "owner@domain.se")
+	accounts := []*model.Account{
+		{Email: "banned@domain.se", Password: "password"},
+		{Email: "blocker@domain.se", Password: "password"},
+		{Email: "friend@domain.se", Password: "password"},
+	}
+	for _, account := range accounts {
+		if err := db.Save(t.Context(), account); err != nil {
+			t.Fatalf("failed to create account: %v", err)
+		}
+	}
+
+	group := saveGroup(t, "owner@domain.se")
+	group.Bans = []*model.Ban{{Email: "banned@domain.se", By: "owner@domain.se"}}
+	if err := db.Save(t.Context(), group); err != nil {
+		t.Fatalf("failed to ban account: %v", err)
+	}
+
+	blocklist := &model.Blocklist{
+		Email:   "blocker@domain.se",
+		Blocked: []*model.Account{{Email: "owner@domain.se"}},
+	}
+	if err := db.Save(t.Context(), blocklist); err != nil {
+		t.Fatalf("failed to create blocklist: %v", err)
+	}
+
+	tests := []tc{
+		{name: "Invite banned account", url: "/groups/1/invite?email=banned@domain.se", wantStatus: 403},
+		{name: "Invite blocking account", url: "/groups/1/invite?email=blocker@domain.se", wantStatus: 403},
+		{name: "Invite friend", url: "/groups/1/invite?email=friend@domain.se", wantStatus: 204},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.url, nil)
+			req.Header.Set("Authorization", token)
+			recorder := httptest.NewRecorder()
+
+			handleGroupInvite(recorder, req)
+
+			if recorder.Result().StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/model"
+)
+
+// readBlocklist returns the blocklist of the email, which is empty for users
+// that have never blocked anyone.
+func readBlocklist(r *http.Request, email string) (*model.Blocklist, error) {
+	blocklist := &model.Blocklist{Email: email, Blocked: []*model.Account{}}
+	if !db.Exists(r.Context(), blocklist) {
+		return blocklist, nil
+	}
+	return db.Read(r.Context(), blocklist)
+}
+
+// handleBlockList lists the accounts the user has blocked. Accounts blocked by
+// tag are listed by tag only, so that blocking never reveals an email.
+func handleBlockList(w http.ResponseWriter, r *http.Request) {
+	blocklist, err := readBlocklist(r, getUserEmailFromToken(r))
+	if err != nil {
+		logger(r).Error(err, "failed to read blocklist")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	blocked := make([]*model.Account, 0, len(blocklist.Blocked))
+	for _, account := range blocklist.Blocked {
+		if account.Tag != "" {
+			blocked = append(blocked, &model.Account{Tag: account.Tag})
+		} else {
+			blocked = append(blocked, &model.Account{Email: account.Email})
+		}
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, blocked); err != nil {
+		logger(r).Error(err, "failed to serialize blocklist")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+// handleBlock blocks an account from inviting the user and from pushing tapps
+// to the user. Blocking an email that has no account succeeds, and also
+// covers an account registered with it later.
+func handleBlock(w http.ResponseWriter, r *http.Request) {
+	email := getUserEmailFromToken(r)
+
+	blockedEmail, err := targetEmail(r)
+	if errors.Is(err, errNoTarget) || blockedEmail == email {
+		logger(r).Error(err, "no email or tag to block found in query parameters")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+	if err != nil {
+		logger(r).Error(err, "no account found matching the blocked tag")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Unknown emails are blocked all the same, answering differently would tell
+	// which emails are registered.
+	blockedAccount, err := db.Read(r.Context(), &model.Account{Email: blockedEmail})
+	if err != nil {
+		blockedAccount = &model.Account{Email: blockedEmail}
+	}
+
+	db.AquireTableLock[*model.Blocklist](r.Context())
+	defer db.ReleaseTableLock[*model.Blocklist]()
+
+	blocklist, err := readBlocklist(r, email)
+	if err != nil {
+		logger(r).Error(err, "failed to read blocklist")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	blocklist.Block(blockedAccount)
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), blocklist); err != nil {
+		logger(r).Error(err, "failed to save blocklist to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleUnblock(w http.ResponseWriter, r *http.Request) {
+	email := getUserEmailFromToken(r)
+
+	blockedEmail, err := targetEmail(r)
+	if errors.Is(err, errNoTarget) {
+		logger(r).Error(err, "no email or tag to unblock found in query parameters")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+	if err != nil {
+		logger(r).Error(err, "no account found matching the blocked tag")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	db.AquireTableLock[*model.Blocklist](r.Context())
+	defer db.ReleaseTableLock[*model.Blocklist]()
+
+	blocklist, err := readBlocklist(r, email)
+	if err != nil {
+		logger(r).Error(err, "failed to read blocklist")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	if !blocklist.Unblock(blockedEmail) {
+		logger(r).Error(err, "account is not blocked")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), blocklist); err != nil {
+		logger(r).Error(err, "failed to save blocklist to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
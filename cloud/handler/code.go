@@ -0,0 +1,354 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+)
+
+const (
+	// Ambiguous characters like O/0 and I/1 are left out, codes are meant to
+	// be read out and typed in.
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	inviteCodeLength   = 8
+)
+
+type inviteCodeRequest struct {
+	Expires int64 `json:"expires"`
+	MaxUses int   `json:"max_uses"`
+}
+
+type joinByCodeRequest struct {
+	Code string `json:"code"`
+}
+
+func newInviteCode() string {
+	code := make([]byte, inviteCodeLength)
+	rand.Read(code)
+	for i := range code {
+		code[i] = inviteCodeAlphabet[int(code[i])%len(inviteCodeAlphabet)]
+	}
+	return string(code)
+}
+
+func normalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// withLink sets the shareable link of the code, links are not stored since the
+// link base may change.
+func withLink(code *model.InviteCode) *model.InviteCode {
+	if base := env.InviteLinkBase.Value(); base != "" {
+		code.Link = base + code.Code
+	}
+	return code
+}
+
+func handleInviteCodeCreate(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if !existingGroup.Can(email, model.PermissionInvite) {
+		logger(r).Error(err, "user is not allowed to invite to the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// The body is optional, an empty one creates a code without limits.
+	request := &inviteCodeRequest{}
+	//nolint:gosec,govet
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil && !errors.Is(err, io.EOF) {
+		logger(r).Error(err, "failed to deserialize invite code request")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonSerErr)
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	if (request.Expires != 0 && request.Expires <= now) || request.MaxUses < 0 {
+		logger(r).Error(err, "invalid invite code limits")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.InviteCode](r.Context())
+	defer db.ReleaseTableLock[*model.InviteCode]()
+
+	code := &model.InviteCode{
+		Code:      newInviteCode(),
+		GroupID:   existingGroup.ID,
+		CreatedBy: email,
+		Created:   now,
+		Expires:   request.Expires,
+		MaxUses:   request.MaxUses,
+	}
+	for db.Exists(r.Context(), code) {
+		code.Code = newInviteCode()
+	}
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), code); err != nil {
+		logger(r).Error(err, "failed to save invite code to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, withLink(code)); err != nil {
+		logger(r).Error(err, "failed to write invite code to response body")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+func handleInviteCodeList(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !existingGroup.Can(getUserEmailFromToken(r), model.PermissionInvite) {
+		logger(r).Error(err, "user is not allowed to invite to the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	codes, err := db.ReadAll[*model.InviteCode](r.Context())
+	if err != nil {
+		logger(r).Error(err, "failed to read invite codes from DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	codes = slices.DeleteFunc(codes, func(c *model.InviteCode) bool {
+		return c.GroupID != existingGroup.ID
+	})
+	for _, code := range codes {
+		withLink(code)
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, codes); err != nil {
+		logger(r).Error(err, "failed to write invite codes to response body")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+func handleInviteCodeRevoke(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	i, err := strconv.Atoi(parts[2])
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !existingGroup.Can(getUserEmailFromToken(r), model.PermissionInvite) {
+		logger(r).Error(err, "user is not allowed to invite to the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	db.AquireTableLock[*model.InviteCode](r.Context())
+	defer db.ReleaseTableLock[*model.InviteCode]()
+
+	code, err := db.Read(
+		r.Context(), &model.InviteCode{Code: normalizeInviteCode(parts[4])},
+	)
+	if err != nil || code.GroupID != existingGroup.ID {
+		logger(r).Error(err, "invite code not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if code.Revoked == 0 {
+		code.Revoked = time.Now().UnixMilli()
+	}
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), code); err != nil {
+		logger(r).Error(err, "failed to save invite code to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGroupJoinByCode adds the caller to the group of the invite code, any
+// pending email invitation to the same group is consumed as well.
+func handleGroupJoinByCode(w http.ResponseWriter, r *http.Request) {
+	request, err := model.Deserialize(r.Body, &joinByCodeRequest{})
+	if err != nil || request.Code == "" {
+		logger(r).Error(err, "failed to deserialize join by code request")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonSerErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+	db.AquireTableLock[*model.InviteCode](r.Context())
+	defer db.ReleaseTableLock[*model.InviteCode]()
+
+	code, err := db.Read(
+		r.Context(), &model.InviteCode{Code: normalizeInviteCode(request.Code)},
+	)
+	if err != nil {
+		logger(r).Error(err, "invite code not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	//nolint:gosec,govet
+	if err := code.Usable(now); err != nil {
+		logger(r).Error(err, "invite code can not be used")
+		w.WriteHeader(http.StatusGone)
+		w.Write(fmt.Appendf(nil, `{"error": %q}`, err.Error()))
+		return
+	}
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: code.GroupID})
+	if err != nil || existingGroup.Archived != 0 {
+		logger(r).Error(err, "group of the invite code not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if existingGroup.IsMember(email) {
+		logger(r).Error(err, "user is already a member of the group")
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	if existingGroup.IsBanned(email) {
+		logger(r).Error(err, "user is banned from the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if groupFull(r.Context(), existingGroup, 0) {
+		logger(r).Error(err, "group has reached its member limit")
+		w.WriteHeader(http.StatusConflict)
+		w.Write(jsonMemberLimitErr)
+		return
+	}
+
+	joiningAccount, err := db.Read(r.Context(), &model.Account{Email: email})
+	if err != nil {
+		logger(r).Error(err, "no email found matching the email")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	existingGroup.Members = append(existingGroup.Members, &model.Member{
+		Email:      email,
+		Tag:        joiningAccount.Tag,
+		Role:       model.RoleMember,
+		Joined:     now,
+		InviteCode: code.Code,
+	})
+
+	if slices.ContainsFunc(
+		existingGroup.Invites, func(a *model.Account) bool { return a.Email == email },
+	) {
+		existingGroup.Invites = slices.DeleteFunc(
+			existingGroup.Invites, func(a *model.Account) bool { return a.Email == email },
+		)
+
+		db.AquireTableLock[*model.Invitation](r.Context())
+		defer db.ReleaseTableLock[*model.Invitation]()
+
+		_ = db.Delete(r.Context(), &model.Invitation{GroupID: existingGroup.ID, Email: email})
+	}
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	// The use is only counted once the member has joined, a failure here lets
+	// one join through uncounted rather than consume a use for nothing.
+	code.Uses++
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), code); err != nil {
+		logger(r).Error(err, "failed to save invite code use to DB")
+	}
+
+	event.Publish(r.Context(), &event.MemberJoined{
+		Time:    now,
+		Group:   existingGroup,
+		Account: &model.Account{Email: joiningAccount.Email, Tag: joiningAccount.Tag},
+	})
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, existingGroup); err != nil {
+		logger(r).Error(err, "failed to write group to response body")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestGroupJoinByCode(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	defer db.Clear[*model.InviteCode](t.Context())
+	env.Parse()
+
+	token := loginAs(t, "email@domain.se")
+	saveGroup(t, "owner@domain.se")
+	codes := []*model.InviteCode{
+		{Code: "ONEUSE22", GroupID: 1, MaxUses: 1},
+		{Code: "REVOKED2", GroupID: 1, Revoked: 1},
+	}
+	for _, code := range codes {
+		if err := db.Save(t.Context(), code); err != nil {
+			t.Fatalf("failed to create invite code: %v", err)
+		}
+	}
+
+	tests := []tc{
+		{name: "Unknown code", body: `{"code":"UNKNOWN2"}`, wantStatus: 404},
+		{name: "Revoked code", body: `{"code":"REVOKED2"}`, wantStatus: 410},
+		{name: "Join with code", body: `{"code":" oneuse22 "}`, wantStatus: 200},
+		{name: "Code used up", body: `{"code":"ONEUSE22"}`, wantStatus: 410},
+		{name: "Missing code", body: `{}`, wantStatus: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/groups/join-by-code", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", token)
+			recorder := httptest.NewRecorder()
+
+			handleGroupJoinByCode(recorder, req)
+
+			if recorder.Result().StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	group, err := db.Read(t.Context(), &model.Group{ID: 1})
+	if err != nil {
+		t.Fatalf("failed to read group: %v", err)
+	}
+	if member := group.Member("email@domain.se"); member == nil || member.InviteCode != "ONEUSE22" {
+		t.Errorf("expected member joined with code ONEUSE22, got %+v", member)
+	}
+}
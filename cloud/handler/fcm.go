@@ -6,11 +6,13 @@ import (
 	"github.com/trebent/tapp-backend/firebase"
 )
 
+// handleFCMUpdate registers the FCM token of the device of the session, an
+// account is notified on every device it is logged in on.
 func handleFCMUpdate(w http.ResponseWriter, r *http.Request) {
 	email := getUserEmailFromToken(r)
 	fcm := r.Header.Get("X-fcm-token")
 
-	firebase.Add(email, fcm)
+	firebase.Add(email, r.Header.Get("Authorization"), fcm)
 
 	w.WriteHeader(http.StatusNoContent)
 }
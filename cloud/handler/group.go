@@ -2,7 +2,7 @@
 package handler
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"regexp"
 	"slices"
@@ -11,9 +11,8 @@ import (
 	"time"
 
 	"github.com/trebent/tapp-backend/db"
-	"github.com/trebent/tapp-backend/firebase"
+	"github.com/trebent/tapp-backend/event"
 	"github.com/trebent/tapp-backend/model"
-	"github.com/trebent/zerologr"
 )
 
 var regexGroupName = regexp.MustCompile(`^[a-zA-Z0-9 _-]{3,30}$`)
@@ -21,29 +20,50 @@ var regexGroupName = regexp.MustCompile(`^[a-zA-Z0-9 _-]{3,30}$`)
 func handleGroupCreate(w http.ResponseWriter, r *http.Request) {
 	newGroup, err := model.Deserialize(r.Body, &model.Group{})
 	if err != nil {
-		zerologr.Error(err, "failed to deserialize group")
+		logger(r).Error(err, "failed to deserialize group")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonSerErr)
 		return
 	}
 
 	if !regexGroupName.MatchString(newGroup.Name) {
-		zerologr.Error(err, "group name is bad")
+		logger(r).Error(err, "group name is bad")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	db.AquireTableLock[*model.Group]()
+	if !validTappLimits(newGroup) {
+		logger(r).Error(err, "group tapp limits are out of bounds")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
 	defer db.ReleaseTableLock[*model.Group]()
 
-	newGroup.ID = db.NextID[*model.Group]()
+	newGroup.ID = db.NextID[*model.Group](r.Context())
 	newGroup.Name = strings.TrimSpace(newGroup.Name)
 	newGroup.Owner = getUserEmailFromToken(r)
 
+	atLimit, err := ownsMaxGroups(r.Context(), newGroup.Owner)
+	if err != nil {
+		logger(r).Error(err, "failed to count owned groups")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+	if atLimit {
+		logger(r).Error(err, "user has reached the owned group limit")
+		w.WriteHeader(http.StatusConflict)
+		w.Write(jsonGroupLimitErr)
+		return
+	}
+
 	//nolint:gosec,govet
-	if err := db.Save(newGroup); err != nil {
-		zerologr.Error(err, "save new group to DB failed")
+	if err := db.Save(r.Context(), newGroup); err != nil {
+		logger(r).Error(err, "save new group to DB failed")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
@@ -52,7 +72,7 @@ func handleGroupCreate(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	//nolint:gosec,govet
 	if err := model.WriteJSON(w, newGroup); err != nil {
-		zerologr.Error(err, "failed to write new group to response body")
+		logger(r).Error(err, "failed to write new group to response body")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonSerErr)
 		return
@@ -60,22 +80,26 @@ func handleGroupCreate(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleGroupList(w http.ResponseWriter, r *http.Request) {
-	groups, err := db.ReadAll[*model.Group]()
+	groups, err := db.ReadAll[*model.Group](r.Context())
 	if err != nil {
-		zerologr.Error(err, "failed to read all groups from DB")
+		logger(r).Error(err, "failed to read all groups from DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	// Owners list their archived groups separately, to restore them.
 	email := getUserEmailFromToken(r)
+	archived := r.URL.Query().Get("archived") == "true"
 	filteredGroups := slices.DeleteFunc(groups, func(g *model.Group) bool {
-		return g.Owner != email &&
-			!slices.ContainsFunc(g.Members, func(a *model.Account) bool { return a.Email == email })
+		if archived {
+			return g.Archived == 0 || g.Owner != email
+		}
+		return g.Archived != 0 || !g.IsMember(email)
 	})
 
 	//nolint:gosec,govet
 	if err := model.WriteJSON(w, filteredGroups); err != nil {
-		zerologr.Error(err, "failed to write all groups to response body")
+		logger(r).Error(err, "failed to write all groups to response body")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonSerErr)
 		return
@@ -87,32 +111,29 @@ func handleGroupGet(w http.ResponseWriter, r *http.Request) {
 
 	i, err := strconv.Atoi(groupID)
 	if err != nil {
-		zerologr.Error(err, "failed to convert path parameter to integer")
+		logger(r).Error(err, "failed to convert path parameter to integer")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	group, err := db.Read(&model.Group{ID: i})
+	group, err := db.Read(r.Context(), &model.Group{ID: i})
 	if err != nil {
-		zerologr.Error(err, "group not found")
+		logger(r).Error(err, "group not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	email := getUserEmailFromToken(r)
-	isMember := email == group.Owner ||
-		slices.ContainsFunc(group.Members, func(a *model.Account) bool { return a.Email == email })
-
-	if !isMember {
-		zerologr.Error(err, "user is not a member of the group")
+	if !group.IsMember(email) {
+		logger(r).Error(err, "user is not a member of the group")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
 	//nolint:gosec,govet
 	if err := model.WriteJSON(w, group); err != nil {
-		zerologr.Error(err, "failed to write group to response body")
+		logger(r).Error(err, "failed to write group to response body")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonSerErr)
 		return
@@ -124,48 +145,55 @@ func handleGroupUpdate(w http.ResponseWriter, r *http.Request) {
 
 	i, err := strconv.Atoi(groupID)
 	if err != nil {
-		zerologr.Error(err, "failed to convert path parameter to integer")
+		logger(r).Error(err, "failed to convert path parameter to integer")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
 	var updatedGroup *model.Group
-	db.AquireTableLock[*model.Group]()
+	db.AquireTableLock[*model.Group](r.Context())
 	defer db.ReleaseTableLock[*model.Group]()
 
-	existingGroup, err := db.Read(&model.Group{ID: i})
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
 	if err != nil {
-		zerologr.Error(err, "existing group not found")
+		logger(r).Error(err, "existing group not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	email := getUserEmailFromToken(r)
-	if email != existingGroup.Owner {
-		zerologr.Error(err, "user is not the owner of the group")
+	if !existingGroup.Can(email, model.PermissionEdit) {
+		logger(r).Error(err, "user is not allowed to edit the group")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
 	updatedGroup, err = model.Deserialize(r.Body, &model.Group{})
 	if err != nil {
-		zerologr.Error(err, "failed to deserialize the group")
+		logger(r).Error(err, "failed to deserialize the group")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonSerErr)
 		return
 	}
 
 	if !regexGroupName.MatchString(updatedGroup.Name) {
-		zerologr.Error(err, "group name is invalid")
+		logger(r).Error(err, "group name is invalid")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	// Don't allow changing ownership, complicates things.
+	if !validTappLimits(updatedGroup) {
+		logger(r).Error(err, "group tapp limits are out of bounds")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	// Ownership changes go through the transfer endpoints.
 	if updatedGroup.Owner != existingGroup.Owner {
-		zerologr.Error(err, "attempted to change the group owner")
+		logger(r).Error(err, "attempted to change the group owner")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
@@ -175,10 +203,15 @@ func handleGroupUpdate(w http.ResponseWriter, r *http.Request) {
 	updatedGroup.Name = strings.TrimSpace(updatedGroup.Name)
 	updatedGroup.Members = existingGroup.Members
 	updatedGroup.Invites = existingGroup.Invites
+	updatedGroup.PendingOwner = existingGroup.PendingOwner
+	updatedGroup.Archived = existingGroup.Archived
+	updatedGroup.Bans = existingGroup.Bans
+	updatedGroup.Avatar = existingGroup.Avatar
+	updatedGroup.OwnerSettings = existingGroup.OwnerSettings
 
 	//nolint:gosec,govet
-	if err := db.Save(updatedGroup); err != nil {
-		zerologr.Error(err, "failed to save updated group to DB")
+	if err := db.Save(r.Context(), updatedGroup); err != nil {
+		logger(r).Error(err, "failed to save updated group to DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
@@ -186,74 +219,62 @@ func handleGroupUpdate(w http.ResponseWriter, r *http.Request) {
 
 	//nolint:gosec,govet
 	if err := model.WriteJSON(w, updatedGroup); err != nil {
-		zerologr.Error(err, "failed to write updated group to response body")
+		logger(r).Error(err, "failed to write updated group to response body")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonSerErr)
 		return
 	}
 }
 
+// handleGroupDelete archives the group, it is purged for good once the restore
+// window has passed.
 func handleGroupDelete(w http.ResponseWriter, r *http.Request) {
 	groupID := r.URL.Path[len("/groups/"):]
 
 	i, err := strconv.Atoi(groupID)
 	if err != nil {
-		zerologr.Error(err, "failed to convert path parameter to integer")
+		logger(r).Error(err, "failed to convert path parameter to integer")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	db.AquireTableLock[*model.Group]()
+	db.AquireTableLock[*model.Group](r.Context())
 	defer db.ReleaseTableLock[*model.Group]()
 
-	existingGroup, err := db.Read(&model.Group{ID: i})
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
 	if err != nil {
-		zerologr.Error(err, "group does not exist")
+		logger(r).Error(err, "group does not exist")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	email := getUserEmailFromToken(r)
-	if email != existingGroup.Owner {
-		zerologr.Error(err, "user is not the owner of the group")
+	if !existingGroup.Can(email, model.PermissionDelete) {
+		logger(r).Error(err, "user is not allowed to delete the group")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
-	db.AquireTableLock[*model.Invitation]()
-	defer db.ReleaseTableLock[*model.Invitation]()
-
-	invites, err := db.ReadAll[*model.Invitation]()
-	if err != nil {
-		zerologr.Error(err, "failed to read invitations")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(jsonDBErr)
-		return
-	}
-
-	for _, invite := range invites {
-		if invite.GroupID == existingGroup.ID {
-			_ = db.Delete(invite)
-		}
-	}
+	now := time.Now().UnixMilli()
+	existingGroup.Archived = now
+	existingGroup.PendingOwner = ""
 
 	//nolint:gosec,govet
-	if err := db.SimpleClear(&model.Tapp{GroupID: existingGroup.ID}); err != nil {
-		zerologr.Error(err, "failed to delete tapps related to group from DB")
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to archive group in DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
 
+	event.Publish(r.Context(), &event.GroupArchived{
+		Time:  now,
+		Group: existingGroup,
+		By:    &model.Account{Email: email},
+	})
+
 	w.WriteHeader(http.StatusNoContent)
-	//nolint:gosec,govet
-	if err := db.Delete(existingGroup); err != nil {
-		zerologr.Error(err, "failed to delete group from DB")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(jsonDBErr)
-		return
-	}
 }
 
 func handleGroupInvite(w http.ResponseWriter, r *http.Request) {
@@ -261,88 +282,124 @@ func handleGroupInvite(w http.ResponseWriter, r *http.Request) {
 
 	i, err := strconv.Atoi(groupID)
 	if err != nil {
-		zerologr.Error(err, "failed to convert path parameter into integer")
+		logger(r).Error(err, "failed to convert path parameter into integer")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	db.AquireTableLock[*model.Group]()
+	db.AquireTableLock[*model.Group](r.Context())
 	defer db.ReleaseTableLock[*model.Group]()
 
-	existingGroup, err := db.Read(&model.Group{ID: i})
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	email := getUserEmailFromToken(r)
-	if email != existingGroup.Owner {
-		zerologr.Error(err, "user is not the owner of the group")
+	if !existingGroup.Can(email, model.PermissionInvite) {
+		logger(r).Error(err, "user is not allowed to invite to the group")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
-	invitedEmail := r.URL.Query().Get("email")
-	if invitedEmail == "" {
-		zerologr.Error(err, "no invitation email found in query parameters")
+	invitedEmail, err := targetEmail(r)
+	if errors.Is(err, errNoTarget) {
+		logger(r).Error(err, "no invitation email or tag found in query parameters")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
+	if err != nil {
+		logger(r).Error(err, "no account found matching the invited tag")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(jsonFormatErr)
+		return
+	}
 
-	invitedAccount, err := db.Read(&model.Account{Email: invitedEmail})
+	invitedAccount, err := db.Read(r.Context(), &model.Account{Email: invitedEmail})
 	if err != nil {
-		zerologr.Error(err, "no email found matching the invited email")
+		logger(r).Error(err, "no email found matching the invited email")
 		w.WriteHeader(http.StatusNotFound)
 		w.Write(jsonFormatErr)
 		return
 	}
 
+	if existingGroup.IsBanned(invitedEmail) {
+		logger(r).Error(err, "invited user is banned from the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if blocklist, err := db.Read(
+		r.Context(), &model.Blocklist{Email: invitedEmail},
+	); err == nil && blocklist.Blocks(email) {
+		logger(r).Error(err, "invited user has blocked the inviter")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
 	if !slices.ContainsFunc(
 		existingGroup.Invites,
 		func(a *model.Account) bool { return a.Email == invitedEmail },
-	) &&
-		!slices.ContainsFunc(
-			existingGroup.Members,
-			func(a *model.Account) bool { return a.Email == invitedEmail },
-		) {
-		existingGroup.Invites = append(existingGroup.Invites, &model.Account{Email: invitedEmail})
-
-		go firebase.SendIndividual(&firebase.TappNotification{
-			Title: fmt.Sprintf(
-				"You have been invited to the group %s!", existingGroup.Name,
-			),
-			Body: fmt.Sprintf(
-				"%s has invited you to join the group %s!", email, existingGroup.Name,
-			),
-			Time:    time.Now().UnixMilli(),
-			Group:   existingGroup,
-			Account: invitedAccount,
-		})
+	) && !existingGroup.IsMember(invitedEmail) {
+		if groupFull(r.Context(), existingGroup, len(existingGroup.Invites)) {
+			logger(r).Error(err, "group has reached its member limit")
+			w.WriteHeader(http.StatusConflict)
+			w.Write(jsonMemberLimitErr)
+			return
+		}
 
-		db.AquireTableLock[*model.Invitation]()
+		db.AquireTableLock[*model.Invitation](r.Context())
 		defer db.ReleaseTableLock[*model.Invitation]()
 
+		atLimit, err := sentMaxInvites(r.Context(), email)
+		if err != nil {
+			logger(r).Error(err, "failed to count pending invitations")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(jsonDBErr)
+			return
+		}
+		if atLimit {
+			logger(r).Error(err, "user has reached the pending invite limit")
+			w.WriteHeader(http.StatusConflict)
+			w.Write(jsonInviteLimitErr)
+			return
+		}
+
+		existingGroup.Invites = append(existingGroup.Invites, &model.Account{Email: invitedEmail})
+
+		now := time.Now()
 		//nolint:govet,gosec
-		if err := db.Save(&model.Invitation{
+		if err := db.Save(r.Context(), &model.Invitation{
 			GroupID:   existingGroup.ID,
 			GroupName: existingGroup.Name,
 			Email:     invitedEmail,
+			InvitedBy: email,
+			Created:   now.UnixMilli(),
+			Expires:   now.Add(invitationTTL()).UnixMilli(),
 		}); err != nil {
-			zerologr.Error(err, "failed to save invitation")
+			logger(r).Error(err, "failed to save invitation")
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write(jsonDBErr)
 			return
 		}
 
 		//nolint:gosec,govet
-		if err := db.Save(existingGroup); err != nil {
-			zerologr.Error(err, "save to DB failed")
+		if err := db.Save(r.Context(), existingGroup); err != nil {
+			logger(r).Error(err, "save to DB failed")
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write(jsonDBErr)
 			return
 		}
+
+		event.Publish(r.Context(), &event.InviteSent{
+			Time:    time.Now().UnixMilli(),
+			Group:   existingGroup,
+			Invitee: &model.Account{Email: invitedAccount.Email, Tag: invitedAccount.Tag},
+			Inviter: &model.Account{Email: email},
+		})
 	}
 
 	w.WriteHeader(http.StatusNoContent)
@@ -353,18 +410,18 @@ func handleGroupJoin(w http.ResponseWriter, r *http.Request) {
 
 	i, err := strconv.Atoi(groupID)
 	if err != nil {
-		zerologr.Error(err, "failed to convert path parameter to integer")
+		logger(r).Error(err, "failed to convert path parameter to integer")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	db.AquireTableLock[*model.Group]()
+	db.AquireTableLock[*model.Group](r.Context())
 	defer db.ReleaseTableLock[*model.Group]()
 
-	existingGroup, err := db.Read(&model.Group{ID: i})
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
 	if err != nil {
-		zerologr.Error(err, "group not found")
+		logger(r).Error(err, "group not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
@@ -375,57 +432,79 @@ func handleGroupJoin(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if !isInvited {
-		zerologr.Error(err, "user was not invited to the group")
+		logger(r).Error(err, "user was not invited to the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if existingGroup.IsBanned(email) {
+		logger(r).Error(err, "user is banned from the group")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
-	invitedAccount, err := db.Read(&model.Account{Email: email})
+	invitedAccount, err := db.Read(r.Context(), &model.Account{Email: email})
 	if err != nil {
-		zerologr.Error(err, "no email found matching the email")
+		logger(r).Error(err, "no email found matching the email")
 		w.WriteHeader(http.StatusNotFound)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	existingGroup.Members = append(existingGroup.Members, &model.Account{Email: email})
-	existingGroup.Invites = slices.DeleteFunc(
-		existingGroup.Invites, func(a *model.Account) bool { return a.Email == email },
+	db.AquireTableLock[*model.Invitation](r.Context())
+	defer db.ReleaseTableLock[*model.Invitation]()
+
+	invitation, err := db.Read(
+		r.Context(), &model.Invitation{GroupID: existingGroup.ID, Email: email},
 	)
+	if err == nil && invitation.Expired(time.Now().UnixMilli()) {
+		logger(r).Error(err, "invitation has expired")
+		w.WriteHeader(http.StatusGone)
+		return
+	}
 
-	go firebase.SendMulticast(&firebase.TappNotification{
-		Title: fmt.Sprintf(
-			"%s has joined the group %s!", invitedAccount.UserIdentifier(), existingGroup.Name,
-		),
-		Body: fmt.Sprintf(
-			"%s has accepted the invitation to join the group %s!",
-			invitedAccount.UserIdentifier(),
-			existingGroup.Name,
-		),
-		Time:    time.Now().UnixMilli(),
-		Group:   existingGroup,
-		Account: invitedAccount,
-	})
+	if groupFull(r.Context(), existingGroup, 0) {
+		logger(r).Error(err, "group has reached its member limit")
+		w.WriteHeader(http.StatusConflict)
+		w.Write(jsonMemberLimitErr)
+		return
+	}
 
-	db.AquireTableLock[*model.Invitation]()
-	defer db.ReleaseTableLock[*model.Invitation]()
+	existingGroup.Members = append(existingGroup.Members, &model.Member{
+		Email:  email,
+		Tag:    invitedAccount.Tag,
+		Role:   model.RoleMember,
+		Joined: time.Now().UnixMilli(),
+	})
+	existingGroup.Invites = slices.DeleteFunc(
+		existingGroup.Invites, func(a *model.Account) bool { return a.Email == email },
+	)
 
 	//nolint:govet,gosec
-	if err := db.Delete(&model.Invitation{GroupID: existingGroup.ID, Email: email}); err != nil {
-		zerologr.Error(err, "failed to delete invitation")
+	if err := db.Delete(
+		r.Context(), &model.Invitation{GroupID: existingGroup.ID, Email: email},
+	); err != nil {
+		logger(r).Error(err, "failed to delete invitation")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
 	//nolint:gosec,govet
-	if err := db.Save(existingGroup); err != nil {
-		zerologr.Error(err, "failed to save group to DB")
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
+
+	event.Publish(r.Context(), &event.MemberJoined{
+		Time:    time.Now().UnixMilli(),
+		Group:   existingGroup,
+		Account: &model.Account{Email: invitedAccount.Email, Tag: invitedAccount.Tag},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func handleGroupDecline(w http.ResponseWriter, r *http.Request) {
@@ -433,18 +512,18 @@ func handleGroupDecline(w http.ResponseWriter, r *http.Request) {
 
 	i, err := strconv.Atoi(groupID)
 	if err != nil {
-		zerologr.Error(err, "failed to convert path parameter to integer")
+		logger(r).Error(err, "failed to convert path parameter to integer")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	db.AquireTableLock[*model.Group]()
+	db.AquireTableLock[*model.Group](r.Context())
 	defer db.ReleaseTableLock[*model.Group]()
 
-	existingGroup, err := db.Read(&model.Group{ID: i})
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
 	if err != nil {
-		zerologr.Error(err, "group not found")
+		logger(r).Error(err, "group not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
@@ -455,7 +534,7 @@ func handleGroupDecline(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if !isInvited {
-		zerologr.Error(err, "user was not invited to the group")
+		logger(r).Error(err, "user was not invited to the group")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
@@ -464,12 +543,14 @@ func handleGroupDecline(w http.ResponseWriter, r *http.Request) {
 		existingGroup.Invites, func(a *model.Account) bool { return a.Email == email },
 	)
 
-	db.AquireTableLock[*model.Invitation]()
+	db.AquireTableLock[*model.Invitation](r.Context())
 	defer db.ReleaseTableLock[*model.Invitation]()
 
 	//nolint:govet,gosec
-	if err := db.Delete(&model.Invitation{GroupID: existingGroup.ID, Email: email}); err != nil {
-		zerologr.Error(err, "failed to delete invitation")
+	if err := db.Delete(
+		r.Context(), &model.Invitation{GroupID: existingGroup.ID, Email: email},
+	); err != nil {
+		logger(r).Error(err, "failed to delete invitation")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
@@ -477,8 +558,8 @@ func handleGroupDecline(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 	//nolint:gosec,govet
-	if err := db.Save(existingGroup); err != nil {
-		zerologr.Error(err, "failed to save group to DB")
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
@@ -490,69 +571,66 @@ func handleGroupLeave(w http.ResponseWriter, r *http.Request) {
 
 	i, err := strconv.Atoi(groupID)
 	if err != nil {
-		zerologr.Error(err, "failed to convert path parameter to integer")
+		logger(r).Error(err, "failed to convert path parameter to integer")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	db.AquireTableLock[*model.Group]()
+	db.AquireTableLock[*model.Group](r.Context())
 	defer db.ReleaseTableLock[*model.Group]()
 
-	existingGroup, err := db.Read(&model.Group{ID: i})
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
 	if err != nil {
-		zerologr.Error(err, "group not found")
+		logger(r).Error(err, "group not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	email := getUserEmailFromToken(r)
-	isMember := slices.ContainsFunc(
-		existingGroup.Members, func(a *model.Account) bool { return a.Email == email },
-	)
+	if existingGroup.Owner == email {
+		handleOwnerLeave(w, r, existingGroup)
+		return
+	}
 
-	if !isMember {
-		zerologr.Error(err, "user is not a member of the group")
+	if existingGroup.Member(email) == nil {
+		logger(r).Error(err, "user is not a member of the group")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	leavingAccount, err := db.Read(&model.Account{Email: email})
+	leavingAccount, err := db.Read(r.Context(), &model.Account{Email: email})
 	if err != nil {
-		zerologr.Error(err, "no email found matching the email")
+		logger(r).Error(err, "no email found matching the email")
 		w.WriteHeader(http.StatusNotFound)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	go firebase.SendMulticast(&firebase.TappNotification{
-		Title: fmt.Sprintf(
-			"%s has left the group %s!",
-			leavingAccount.UserIdentifier(),
-			existingGroup.Name,
-		),
-		Body: fmt.Sprintf(
-			"%s has decided to leave the group %s!",
-			leavingAccount.UserIdentifier(),
-			existingGroup.Name,
-		),
-		Time:    time.Now().UnixMilli(),
-		Group:   existingGroup,
-		Account: leavingAccount,
-	})
-
 	existingGroup.Members = slices.DeleteFunc(
-		existingGroup.Members, func(a *model.Account) bool { return a.Email == email },
+		existingGroup.Members, func(m *model.Member) bool { return m.Email == email },
 	)
+	if existingGroup.PendingOwner == email {
+		existingGroup.PendingOwner = ""
+	}
 
-	w.WriteHeader(http.StatusNoContent)
 	//nolint:gosec,govet
-	if err := db.Save(existingGroup); err != nil {
-		zerologr.Error(err, "saving the group to DB failed")
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "saving the group to DB failed")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
+
+	cancelSchedules(r.Context(), existingGroup.ID, email)
+
+	event.Publish(r.Context(), &event.MemberLeft{
+		Time:    time.Now().UnixMilli(),
+		Group:   existingGroup,
+		Account: &model.Account{Email: leavingAccount.Email, Tag: leavingAccount.Tag},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func handleGroupKick(w http.ResponseWriter, r *http.Request) {
@@ -560,108 +638,114 @@ func handleGroupKick(w http.ResponseWriter, r *http.Request) {
 
 	i, err := strconv.Atoi(groupID)
 	if err != nil {
-		zerologr.Error(err, "failed to convert path parameter to integer")
+		logger(r).Error(err, "failed to convert path parameter to integer")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	db.AquireTableLock[*model.Group]()
+	db.AquireTableLock[*model.Group](r.Context())
 	defer db.ReleaseTableLock[*model.Group]()
-	db.AquireTableLock[*model.Invitation]()
+	db.AquireTableLock[*model.Invitation](r.Context())
 	defer db.ReleaseTableLock[*model.Invitation]()
 
-	existingGroup, err := db.Read(&model.Group{ID: i})
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
 	if err != nil {
-		zerologr.Error(err, "group not found")
+		logger(r).Error(err, "group not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	email := getUserEmailFromToken(r)
-	if email != existingGroup.Owner {
-		zerologr.Error(err, "user is not the group owner")
+	if !existingGroup.Can(email, model.PermissionKick) {
+		logger(r).Error(err, "user is not allowed to kick from the group")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
-	kickedEmail := r.URL.Query().Get("email")
-	if kickedEmail == "" {
-		zerologr.Error(err, "no email to kick found in query parameters")
+	kickedEmail, err := targetEmail(r)
+	if errors.Is(err, errNoTarget) {
+		logger(r).Error(err, "no email or tag to kick found in query parameters")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
+	if err != nil {
+		logger(r).Error(err, "no account found matching the kicked tag")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
 
-	foundMember := slices.ContainsFunc(
-		existingGroup.Members,
-		func(a *model.Account) bool { return a.Email == kickedEmail },
-	)
-	if !foundMember {
-		zerologr.Error(err, "group has no member with that email")
+	if existingGroup.Member(kickedEmail) == nil {
+		logger(r).Error(err, "group has no member with that email")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	kickedAccount, err := db.Read(&model.Account{Email: email})
+	if !existingGroup.Outranks(email, kickedEmail) {
+		logger(r).Error(err, "user can only kick members of a lower role")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	kickedAccount, err := db.Read(r.Context(), &model.Account{Email: kickedEmail})
 	if err != nil {
-		zerologr.Error(err, "no email found matching the email")
+		logger(r).Error(err, "no email found matching the email")
 		w.WriteHeader(http.StatusNotFound)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	go firebase.SendMulticast(&firebase.TappNotification{
-		Title: fmt.Sprintf(
-			"%s has been kicked from the group %s!",
-			kickedAccount.UserIdentifier(),
-			existingGroup.Name,
-		),
-		Body: fmt.Sprintf(
-			"%s has been kicked from the group %s!",
-			kickedAccount.UserIdentifier(),
-			existingGroup.Name,
-		),
-		Time:    time.Now().UnixMilli(),
-		Group:   existingGroup,
-		Account: &model.Account{Email: email},
-	})
-
-	existingGroup.Members = slices.DeleteFunc(
-		existingGroup.Members, func(a *model.Account) bool { return a.Email == kickedEmail },
-	)
+	if r.URL.Query().Get("ban") == "true" {
+		existingGroup.Ban(kickedAccount, email, time.Now().UnixMilli())
+	} else {
+		existingGroup.Members = slices.DeleteFunc(
+			existingGroup.Members, func(m *model.Member) bool { return m.Email == kickedEmail },
+		)
+	}
 
-	w.WriteHeader(http.StatusNoContent)
 	//nolint:gosec,govet
-	if err := db.Save(existingGroup); err != nil {
-		zerologr.Error(err, "save group to DB failed")
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "save group to DB failed")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
+
+	cancelSchedules(r.Context(), existingGroup.ID, kickedEmail)
+
+	event.Publish(r.Context(), &event.MemberKicked{
+		Time:    time.Now().UnixMilli(),
+		Group:   existingGroup,
+		Account: &model.Account{Email: kickedAccount.Email, Tag: kickedAccount.Tag},
+		By:      &model.Account{Email: email},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func handleGroupInvitesList(w http.ResponseWriter, r *http.Request) {
-	db.AquireTableLock[*model.Invitation]()
+	db.AquireTableLock[*model.Invitation](r.Context())
 	defer db.ReleaseTableLock[*model.Invitation]()
 
 	email := getUserEmailFromToken(r)
 
-	invites, err := db.ReadAll[*model.Invitation]()
+	invites, err := db.ReadAll[*model.Invitation](r.Context())
 	if err != nil {
-		zerologr.Error(err, "failed to read from invitations table")
+		logger(r).Error(err, "failed to read from invitations table")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
 
+	now := time.Now().UnixMilli()
 	filteredInvites := slices.DeleteFunc(invites, func(i *model.Invitation) bool {
-		return i.Email != email
+		return i.Email != email || i.Expired(now)
 	})
 
 	//nolint:gosec,govet
 	if err := model.WriteJSON(w, filteredInvites); err != nil {
-		zerologr.Error(err, "failed to serialize invitations")
+		logger(r).Error(err, "failed to serialize invitations")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonSerErr)
 		return
@@ -12,8 +12,8 @@ import (
 )
 
 func TestGroupCreate(t *testing.T) {
-	defer db.Clear[*model.Account]()
-	defer db.Clear[*model.Group]()
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
 	env.Parse()
 
 	req := httptest.NewRequest("POST", "/accounts", strings.NewReader(`{"email":"email@domain.se","password":"password"}`))
@@ -75,26 +75,26 @@ type groupListTC struct {
 }
 
 func TestGroupList(t *testing.T) {
-	defer db.Clear[*model.Group]()
-	defer db.Clear[*model.Account]()
+	defer db.Clear[*model.Group](t.Context())
+	defer db.Clear[*model.Account](t.Context())
 	env.Parse()
 
-	if err := db.Save(&model.Account{Email: "email@domain.se", Password: "password"}); err != nil {
+	if err := db.Save(t.Context(), &model.Account{Email: "email@domain.se", Password: "password"}); err != nil {
 		t.Fatalf("failed to create account: %v", err)
 		return
 	}
 
-	if err := db.Save(&model.Account{Email: "email3@domain.se", Password: "password"}); err != nil {
+	if err := db.Save(t.Context(), &model.Account{Email: "email3@domain.se", Password: "password"}); err != nil {
 		t.Fatalf("failed to create account: %v", err)
 		return
 	}
 
-	if err := db.Save(&model.Group{ID: 1, Name: "Group 1", Owner: "email@domain.se"}); err != nil {
+	if err := db.Save(t.Context(), &model.Group{ID: 1, Name: "Group 1", Owner: "email@domain.se"}); err != nil {
 		t.Fatalf("failed to create group: %v", err)
 		return
 	}
 
-	if err := db.Save(&model.Group{ID: 2, Name: "Group 2", Owner: "email2@domain.se", Members: []*model.Account{{Email: "email@domain.se"}}}); err != nil {
+	if err := db.Save(t.Context(), &model.Group{ID: 2, Name: "Group 2", Owner: "email2@domain.se", Members: []*model.Member{{Email: "email@domain.se"}}}); err != nil {
 		t.Fatalf("failed to create group: %v", err)
 		return
 	}
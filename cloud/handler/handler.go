@@ -3,24 +3,109 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/trebent/tapp-backend/db"
 	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/metrics"
 	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/tapp-backend/tracing"
 	"github.com/trebent/zerologr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var jsonSerErr = []byte(`{"error": "serializer error"}`)
 var jsonFormatErr = []byte(`{"error": "format error"}`)
 var jsonDBErr = []byte(`{"error": "DB error"}`)
 
+var tracer = tracing.Tracer("handler")
+
+// logger returns a logger that tags log lines with the trace of the request.
+func logger(r *http.Request) logr.Logger {
+	return tracing.Logger(r.Context())
+}
+
 func logWrapper(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		zerologr.Info(r.Method + " " + r.URL.Path)
+		logger(r).Info(r.Method + " " + r.URL.Path)
 		h.ServeHTTP(w, r)
 	})
 }
 
+func traceWrapper(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		req := r.WithContext(ctx)
+		h.ServeHTTP(recorder, req)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		// The mux sets the pattern on the request it was given.
+		span.SetName(r.Method + " " + req.Pattern)
+		span.SetAttributes(
+			attribute.String("http.route", req.Pattern),
+			attribute.Int("http.response.status_code", recorder.status),
+		)
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+	})
+}
+
+// statusRecorder captures the response status code for instrumentation.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func metricsWrapper(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		// The pattern is set by the mux, unmatched requests get an empty route.
+		metrics.ObserveHTTP(r.Pattern, r.Method, recorder.status, time.Since(start))
+	})
+}
+
 //nolint:gocognit,funlen
 func Handler() http.Handler {
 	mux := http.NewServeMux()
@@ -31,8 +116,27 @@ func Handler() http.Handler {
 		w.WriteHeader(http.StatusNoContent)
 	})
 
+	// Liveness and readiness probes
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		handleLivez(w, r)
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		handleReadyz(w, r)
+	})
+
 	mux.HandleFunc("/admin/debug", func(w http.ResponseWriter, r *http.Request) {
-		zerologr.Info("outputing debug info...")
+		logger(r).Info("outputing debug info...")
 
 		if r.Header.Get("X-tapp-admin-key") != env.AdminKey.Value() {
 			w.WriteHeader(http.StatusForbidden)
@@ -44,46 +148,86 @@ func Handler() http.Handler {
 			Groups           []*model.Group           `json:"groups"`
 			TappsByGroupName map[string][]*model.Tapp `json:"tapps_by_group_name"`
 			Invites          []*model.Invitation      `json:"invitations"`
+			InviteCodes      []*model.InviteCode      `json:"invite_codes"`
+			EventCounts      map[string]int64         `json:"event_counts"`
 		}{
 			TappsByGroupName: map[string][]*model.Tapp{},
 		}
 
-		accounts, _ := db.ReadAll[*model.Account]()
+		accounts, _ := db.ReadAll[*model.Account](r.Context())
 		summary.Accounts = accounts
 
-		groups, _ := db.ReadAll[*model.Group]()
+		groups, _ := db.ReadAll[*model.Group](r.Context())
 		summary.Groups = groups
 
 		for _, group := range groups {
-			tapps, _ := db.SimpleRead(&model.Tapp{GroupID: group.ID})
+			tapps, _ := db.SimpleRead(r.Context(), &model.Tapp{GroupID: group.ID})
 			summary.TappsByGroupName[group.Name] = tapps
 		}
 
-		invites, _ := db.ReadAll[*model.Invitation]()
+		invites, _ := db.ReadAll[*model.Invitation](r.Context())
 		summary.Invites = invites
 
+		codes, _ := db.ReadAll[*model.InviteCode](r.Context())
+		summary.InviteCodes = codes
+		summary.EventCounts = metrics.EventCounts()
+
 		_ = model.WriteJSON(w, summary)
 	})
 
 	mux.HandleFunc("/admin/clear", func(w http.ResponseWriter, r *http.Request) {
-		zerologr.Info("clearing DB tables...")
+		logger(r).Info("clearing DB tables...")
 
 		if r.Header.Get("X-tapp-admin-key") != env.AdminKey.Value() {
 			w.WriteHeader(http.StatusForbidden)
 			return
 		}
 
-		groups, _ := db.ReadAll[*model.Group]()
+		groups, _ := db.ReadAll[*model.Group](r.Context())
 
 		for _, group := range groups {
-			_ = db.SimpleClear(&model.Tapp{GroupID: group.ID})
+			_ = db.SimpleClear(r.Context(), &model.Tapp{GroupID: group.ID})
+			_ = db.SimpleClear(r.Context(), &model.TappAck{GroupID: group.ID})
+		}
+
+		_ = db.Clear[*model.Group](r.Context())
+		_ = db.Clear[*model.Invitation](r.Context())
+		_ = db.Clear[*model.InviteCode](r.Context())
+		_ = db.Clear[*model.Account](r.Context())
+		_ = db.Clear[*model.AccountTag](r.Context())
+		_ = db.Clear[*model.Blocklist](r.Context())
+		_ = db.Clear[*model.Quota](r.Context())
+		_ = db.Clear[*model.GroupStats](r.Context())
+		_ = db.Clear[*model.Schedule](r.Context())
+		_ = db.Clear[*model.Preferences](r.Context())
+		_ = db.Clear[*model.Digest](r.Context())
+		_ = db.Clear[*model.OutboxEntry](r.Context())
+		_ = db.Clear[*model.DeadLetter](r.Context())
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/admin/quotas/{email}", func(w http.ResponseWriter, r *http.Request) {
+		// GET, PUT, DELETE
+		if r.Body != nil {
+			defer r.Body.Close()
 		}
 
-		_ = db.Clear[*model.Group]()
-		_ = db.Clear[*model.Invitation]()
-		_ = db.Clear[*model.Account]()
+		if r.Header.Get("X-tapp-admin-key") != env.AdminKey.Value() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
 
-		w.WriteHeader(http.StatusNoContent)
+		switch r.Method {
+		case http.MethodGet:
+			handleQuotaGet(w, r)
+		case http.MethodPut:
+			handleQuotaUpdate(w, r)
+		case http.MethodDelete:
+			handleQuotaDelete(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
 	})
 
 	// Account endpoints
@@ -141,6 +285,58 @@ func Handler() http.Handler {
 		}
 	})
 
+	mux.HandleFunc("/users/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		// GET
+		if !authenticated(w, r) {
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		handleUserGet(w, r)
+	})
+
+	mux.HandleFunc("/blocks", func(w http.ResponseWriter, r *http.Request) {
+		// GET, POST, DELETE
+		if !authenticated(w, r) {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			handleBlockList(w, r)
+		case http.MethodPost:
+			handleBlock(w, r)
+		case http.MethodDelete:
+			handleUnblock(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/preferences", func(w http.ResponseWriter, r *http.Request) {
+		// GET, PUT
+		if r.Body != nil {
+			defer r.Body.Close()
+		}
+
+		if !authenticated(w, r) {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			handlePreferencesGet(w, r)
+		case http.MethodPut:
+			handlePreferencesUpdate(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
 	// Auth endpoints
 	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
 		// POST
@@ -186,7 +382,7 @@ func Handler() http.Handler {
 		}
 	})
 
-	mux.HandleFunc("/groups/{group}", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/groups/{group}", archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
 		// GET, PUT, DELETE
 		if r.Body != nil {
 			defer r.Body.Close()
@@ -206,14 +402,56 @@ func Handler() http.Handler {
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
-	})
-
-	mux.HandleFunc("/groups/{group}/invite", func(w http.ResponseWriter, r *http.Request) {
+	}))
+
+	mux.HandleFunc(
+		"/groups/{group}/avatar",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// GET, PUT, DELETE
+			if r.Body != nil {
+				defer r.Body.Close()
+			}
+
+			if !authenticated(w, r) {
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				handleGroupAvatarGet(w, r)
+			case http.MethodPut:
+				handleGroupAvatarUpload(w, r)
+			case http.MethodDelete:
+				handleGroupAvatarDelete(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/me",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// PUT
+			if r.Body != nil {
+				defer r.Body.Close()
+			}
+
+			if !authenticated(w, r) {
+				return
+			}
+
+			if r.Method != http.MethodPut {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleGroupSettingsUpdate(w, r)
+		}),
+	)
+
+	mux.HandleFunc("/groups/{group}/restore", func(w http.ResponseWriter, r *http.Request) {
 		// POST
-		if r.Body != nil {
-			defer r.Body.Close()
-		}
-
 		if !authenticated(w, r) {
 			return
 		}
@@ -223,10 +461,54 @@ func Handler() http.Handler {
 			return
 		}
 
-		handleGroupInvite(w, r)
+		handleGroupRestore(w, r)
 	})
 
-	mux.HandleFunc("/groups/{group}/join", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc(
+		"/groups/{group}/invite",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST
+			if r.Body != nil {
+				defer r.Body.Close()
+			}
+
+			if !authenticated(w, r) {
+				return
+			}
+
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleGroupInvite(w, r)
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/join",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST
+			if r.Body != nil {
+				defer r.Body.Close()
+			}
+
+			if !authenticated(w, r) {
+				return
+			}
+
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleGroupJoin(w, r)
+		}),
+	)
+
+	// Declining only removes the caller's own invitation, so it stays open for
+	// archived groups.
+	mux.HandleFunc("/groups/{group}/decline", func(w http.ResponseWriter, r *http.Request) {
 		// POST
 		if r.Body != nil {
 			defer r.Body.Close()
@@ -241,10 +523,162 @@ func Handler() http.Handler {
 			return
 		}
 
-		handleGroupJoin(w, r)
+		handleGroupDecline(w, r)
 	})
 
-	mux.HandleFunc("/groups/{group}/decline", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc(
+		"/groups/{group}/leave",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST
+			if r.Body != nil {
+				defer r.Body.Close()
+			}
+
+			if !authenticated(w, r) {
+				return
+			}
+
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleGroupLeave(w, r)
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/kick",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST
+			if r.Body != nil {
+				defer r.Body.Close()
+			}
+
+			if !authenticated(w, r) {
+				return
+			}
+
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleGroupKick(w, r)
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/promote",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST
+			if !authenticated(w, r) {
+				return
+			}
+
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleGroupPromote(w, r)
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/demote",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST
+			if !authenticated(w, r) {
+				return
+			}
+
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleGroupDemote(w, r)
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/transfer",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST, DELETE
+			if !authenticated(w, r) {
+				return
+			}
+
+			switch r.Method {
+			case http.MethodPost:
+				handleGroupTransfer(w, r)
+			case http.MethodDelete:
+				handleGroupTransferCancel(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/transfer/accept",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST
+			if !authenticated(w, r) {
+				return
+			}
+
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleGroupTransferAccept(w, r)
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/codes",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST, GET
+			if r.Body != nil {
+				defer r.Body.Close()
+			}
+
+			if !authenticated(w, r) {
+				return
+			}
+
+			switch r.Method {
+			case http.MethodPost:
+				handleInviteCodeCreate(w, r)
+			case http.MethodGet:
+				handleInviteCodeList(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/codes/{code}",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// DELETE
+			if !authenticated(w, r) {
+				return
+			}
+
+			if r.Method != http.MethodDelete {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleInviteCodeRevoke(w, r)
+		}),
+	)
+
+	mux.HandleFunc("/groups/join-by-code", func(w http.ResponseWriter, r *http.Request) {
 		// POST
 		if r.Body != nil {
 			defer r.Body.Close()
@@ -259,46 +693,165 @@ func Handler() http.Handler {
 			return
 		}
 
-		handleGroupDecline(w, r)
+		handleGroupJoinByCode(w, r)
 	})
 
-	mux.HandleFunc("/groups/{group}/leave", func(w http.ResponseWriter, r *http.Request) {
-		// POST
-		if r.Body != nil {
-			defer r.Body.Close()
-		}
+	mux.HandleFunc(
+		"/groups/{group}/invitations",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// GET, DELETE
+			if !authenticated(w, r) {
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				handleGroupSentInvitesList(w, r)
+			case http.MethodDelete:
+				handleGroupInviteRevoke(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/bans",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST, GET, DELETE
+			if !authenticated(w, r) {
+				return
+			}
+
+			switch r.Method {
+			case http.MethodPost:
+				handleGroupBan(w, r)
+			case http.MethodGet:
+				handleGroupBanList(w, r)
+			case http.MethodDelete:
+				handleGroupUnban(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}),
+	)
 
+	mux.HandleFunc("/groups/invitations", func(w http.ResponseWriter, r *http.Request) {
 		if !authenticated(w, r) {
 			return
 		}
 
-		if r.Method != http.MethodPost {
+		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 
-		handleGroupLeave(w, r)
+		handleGroupInvitesList(w, r)
 	})
 
-	mux.HandleFunc("/groups/{group}/kick", func(w http.ResponseWriter, r *http.Request) {
-		// POST
-		if r.Body != nil {
-			defer r.Body.Close()
-		}
-
+	// TAPP endpoints
+	mux.HandleFunc(
+		"/groups/{group}/tapp",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST
+			if r.Body != nil {
+				defer r.Body.Close()
+			}
+
+			if !authenticated(w, r) {
+				return
+			}
+
+			switch r.Method {
+			case http.MethodPost:
+				handleTapp(w, r)
+			case http.MethodGet:
+				handleTappGet(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}),
+	)
+
+	mux.HandleFunc("/groups/{group}/tapp/{tapp}", func(w http.ResponseWriter, r *http.Request) {
+		// GET
 		if !authenticated(w, r) {
 			return
 		}
 
-		if r.Method != http.MethodPost {
+		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 
-		handleGroupKick(w, r)
+		handleTappThread(w, r)
 	})
 
-	mux.HandleFunc("/groups/invitations", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc(
+		"/groups/{group}/tapp/{tapp}/ack",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST
+			if !authenticated(w, r) {
+				return
+			}
+
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleTappAck(w, r)
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/schedules",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// POST, GET
+			if r.Body != nil {
+				defer r.Body.Close()
+			}
+
+			if !authenticated(w, r) {
+				return
+			}
+
+			switch r.Method {
+			case http.MethodPost:
+				handleScheduleCreate(w, r)
+			case http.MethodGet:
+				handleScheduleList(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}),
+	)
+
+	mux.HandleFunc(
+		"/groups/{group}/schedules/{schedule}",
+		archiveWrapper(func(w http.ResponseWriter, r *http.Request) {
+			// PUT, DELETE
+			if r.Body != nil {
+				defer r.Body.Close()
+			}
+
+			if !authenticated(w, r) {
+				return
+			}
+
+			switch r.Method {
+			case http.MethodPut:
+				handleScheduleUpdate(w, r)
+			case http.MethodDelete:
+				handleScheduleDelete(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}),
+	)
+
+	mux.HandleFunc("/groups/{group}/stats", func(w http.ResponseWriter, r *http.Request) {
+		// GET
 		if !authenticated(w, r) {
 			return
 		}
@@ -308,28 +861,21 @@ func Handler() http.Handler {
 			return
 		}
 
-		handleGroupInvitesList(w, r)
+		handleGroupStats(w, r)
 	})
 
-	// TAPP endpoints
-	mux.HandleFunc("/groups/{group}/tapp", func(w http.ResponseWriter, r *http.Request) {
-		// POST
-		if r.Body != nil {
-			defer r.Body.Close()
-		}
-
+	mux.HandleFunc("/groups/{group}/events", func(w http.ResponseWriter, r *http.Request) {
+		// GET
 		if !authenticated(w, r) {
 			return
 		}
 
-		switch r.Method {
-		case http.MethodPost:
-			handleTapp(w, r)
-		case http.MethodGet:
-			handleTappGet(w, r)
-		default:
+		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
 		}
+
+		handleGroupStream(w, r)
 	})
 
 	// FCM
@@ -347,5 +893,5 @@ func Handler() http.Handler {
 		handleFCMUpdate(w, r)
 	})
 
-	return logWrapper(mux)
+	return traceWrapper(logWrapper(metricsWrapper(mux)))
 }
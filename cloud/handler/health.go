@@ -0,0 +1,139 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/firebase"
+	"github.com/trebent/tapp-backend/model"
+)
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+//nolint:gochecknoglobals
+var shuttingDown = atomic.Bool{}
+
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type readiness struct {
+	Status string                  `json:"status"`
+	Checks map[string]*checkResult `json:"checks"`
+}
+
+// SetShuttingDown makes the readiness probe fail, so that traffic is drained
+// before the server stops.
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+func handleLivez(w http.ResponseWriter, r *http.Request) {
+	logger(r).V(1).Info("liveness check OK")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	result := &readiness{
+		Status: statusOK,
+		Checks: map[string]*checkResult{
+			"shutdown":      checkShutdown(),
+			"filesystem":    checkFileSystem(),
+			"tables":        checkTables(r.Context()),
+			"notifications": checkNotifications(),
+		},
+	}
+
+	for name, check := range result.Checks {
+		if check.Status != statusOK {
+			logger(r).Info("readiness check failed", "check", name, "error", check.Error)
+			result.Status = statusFail
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Status != statusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, result); err != nil {
+		logger(r).Error(err, "failed to serialize readiness")
+	}
+}
+
+func checkShutdown() *checkResult {
+	if shuttingDown.Load() {
+		return &checkResult{Status: statusFail, Error: "server is shutting down"}
+	}
+	return &checkResult{Status: statusOK}
+}
+
+// checkFileSystem verifies that the data directory can be written to.
+func checkFileSystem() *checkResult {
+	if err := os.MkdirAll(env.FileSystem.Value(), 0o755); err != nil {
+		return &checkResult{Status: statusFail, Error: err.Error()}
+	}
+
+	f, err := os.CreateTemp(env.FileSystem.Value(), ".readyz-*")
+	if err != nil {
+		return &checkResult{Status: statusFail, Error: err.Error()}
+	}
+	f.Close()
+
+	if err := os.Remove(f.Name()); err != nil {
+		return &checkResult{Status: statusFail, Error: err.Error()}
+	}
+	return &checkResult{Status: statusOK}
+}
+
+// checkTables verifies that the tables can be read and parsed.
+func checkTables(ctx context.Context) *checkResult {
+	checks := []func() error{
+		func() error { return checkTable[*model.Account](ctx) },
+		func() error { return checkTable[*model.Group](ctx) },
+		func() error { return checkTable[*model.Invitation](ctx) },
+		func() error { return checkTable[*model.InviteCode](ctx) },
+		func() error { return checkTable[*model.AccountTag](ctx) },
+		func() error { return checkTable[*model.Blocklist](ctx) },
+		func() error { return checkTable[*model.Quota](ctx) },
+		func() error { return checkTable[*model.GroupStats](ctx) },
+		func() error { return checkTable[*model.Schedule](ctx) },
+		func() error { return checkTable[*model.Preferences](ctx) },
+		func() error { return checkTable[*model.Digest](ctx) },
+		func() error { return checkTable[*model.OutboxEntry](ctx) },
+		func() error { return checkTable[*model.DeadLetter](ctx) },
+	}
+
+	for _, check := range checks {
+		if err := check(); err != nil {
+			return &checkResult{Status: statusFail, Error: err.Error()}
+		}
+	}
+	return &checkResult{Status: statusOK}
+}
+
+// checkTable reads the table under its lock, so that a table being written is
+// not mistaken for a broken one.
+func checkTable[T db.Model](ctx context.Context) error {
+	db.AquireTableLock[T](ctx)
+	defer db.ReleaseTableLock[T]()
+
+	_, err := db.ReadAll[T](ctx)
+	return err
+}
+
+func checkNotifications() *checkResult {
+	if err := firebase.Ready(); err != nil {
+		return &checkResult{Status: statusFail, Error: err.Error()}
+	}
+	return &checkResult{Status: statusOK}
+}
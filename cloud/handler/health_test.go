@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/trebent/tapp-backend/env"
+)
+
+func TestReadyz(t *testing.T) {
+	env.Parse()
+	defer shuttingDown.Store(false)
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	recorder := httptest.NewRecorder()
+	handleReadyz(recorder, req)
+
+	result := &readiness{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	for _, name := range []string{"shutdown", "filesystem", "tables"} {
+		if result.Checks[name].Status != statusOK {
+			t.Errorf("check %s: got status %s, want %s", name, result.Checks[name].Status, statusOK)
+		}
+	}
+
+	// No messaging client is initialized in tests.
+	if result.Checks["notifications"].Status != statusFail {
+		t.Errorf("got notifications status %s, want %s", result.Checks["notifications"].Status, statusFail)
+	}
+	if recorder.Code != 503 {
+		t.Errorf("got status %d, want %d", recorder.Code, 503)
+	}
+
+	SetShuttingDown()
+	recorder = httptest.NewRecorder()
+	handleReadyz(recorder, req)
+
+	result = &readiness{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if result.Checks["shutdown"].Status != statusFail {
+		t.Errorf("got shutdown status %s, want %s", result.Checks["shutdown"].Status, statusFail)
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/model"
+)
+
+// loginAs creates an account with the email, unless it exists, and returns the
+// token of a new session of it.
+func loginAs(t *testing.T, email string) string {
+	t.Helper()
+
+	account := &model.Account{Email: email, Password: "password"}
+	if !db.Exists(t.Context(), account) {
+		if err := db.Save(t.Context(), account); err != nil {
+			t.Fatalf("failed to create account: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(
+		`{"email":"`+email+`","password":"password"}`,
+	))
+	recorder := httptest.NewRecorder()
+	handleLogin(recorder, req)
+
+	token := recorder.Header().Get("Authorization")
+	if token == "" {
+		t.Fatalf("failed to log in as %s, got status %d", email, recorder.Code)
+	}
+	return token
+}
+
+// saveGroup creates group 1 with the owner and members.
+func saveGroup(t *testing.T, owner string, members ...string) *model.Group {
+	t.Helper()
+
+	group := &model.Group{ID: 1, Name: "Group 1", Owner: owner, Members: []*model.Member{}}
+	for _, email := range members {
+		group.Members = append(group.Members, &model.Member{Email: email})
+	}
+	if err := db.Save(t.Context(), group); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	return group
+}
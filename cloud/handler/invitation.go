@@ -0,0 +1,208 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/tapp-backend/tracing"
+)
+
+func invitationTTL() time.Duration {
+	return time.Duration(env.InvitationTTLHours.Value()) * time.Hour
+}
+
+// handleGroupSentInvitesList lists the pending invitations of a group, for the
+// members that are allowed to invite.
+func handleGroupSentInvitesList(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !existingGroup.Can(getUserEmailFromToken(r), model.PermissionInvite) {
+		logger(r).Error(err, "user is not allowed to invite to the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	invites, err := db.ReadAll[*model.Invitation](r.Context())
+	if err != nil {
+		logger(r).Error(err, "failed to read from invitations table")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	filteredInvites := slices.DeleteFunc(invites, func(i *model.Invitation) bool {
+		return i.GroupID != existingGroup.ID || i.Expired(now)
+	})
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, filteredInvites); err != nil {
+		logger(r).Error(err, "failed to serialize invitations")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+// handleGroupInviteRevoke cancels the pending invitation of the email given in
+// the query parameters.
+func handleGroupInviteRevoke(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !existingGroup.Can(getUserEmailFromToken(r), model.PermissionInvite) {
+		logger(r).Error(err, "user is not allowed to invite to the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	invitedEmail := r.URL.Query().Get("email")
+	if invitedEmail == "" {
+		logger(r).Error(err, "no invitation email found in query parameters")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	if !slices.ContainsFunc(
+		existingGroup.Invites, func(a *model.Account) bool { return a.Email == invitedEmail },
+	) {
+		logger(r).Error(err, "email has not been invited to the group")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	existingGroup.Invites = slices.DeleteFunc(
+		existingGroup.Invites, func(a *model.Account) bool { return a.Email == invitedEmail },
+	)
+
+	db.AquireTableLock[*model.Invitation](r.Context())
+	defer db.ReleaseTableLock[*model.Invitation]()
+
+	// Invitations from before the invitations table was kept in sync with the
+	// group may be missing, the group is what matters.
+	_ = db.Delete(r.Context(), &model.Invitation{GroupID: existingGroup.ID, Email: invitedEmail})
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SweepInvitations removes expired invitations periodically, until the context
+// is done.
+func SweepInvitations(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(env.InvitationSweepMinutes.Value()) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			//nolint:gosec,govet
+			if err := sweepInvitations(ctx); err != nil {
+				tracing.Logger(ctx).Error(err, "failed to sweep invitations")
+			}
+		}
+	}
+}
+
+// sweepInvitations deletes expired invitations and removes them from the
+// invites of their groups. Invitations created before they had an expiry are
+// given one, counting from the sweep.
+func sweepInvitations(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "sweep invitations")
+	defer span.End()
+
+	db.AquireTableLock[*model.Group](ctx)
+	defer db.ReleaseTableLock[*model.Group]()
+	db.AquireTableLock[*model.Invitation](ctx)
+	defer db.ReleaseTableLock[*model.Invitation]()
+
+	invites, err := db.ReadAll[*model.Invitation](ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expired := 0
+	for _, invite := range invites {
+		if invite.Expires == 0 {
+			invite.Expires = now.Add(invitationTTL()).UnixMilli()
+			if err := db.Save(ctx, invite); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !invite.Expired(now.UnixMilli()) {
+			continue
+		}
+
+		if err := db.Delete(ctx, invite); err != nil {
+			return err
+		}
+		expired++
+
+		group, err := db.Read(ctx, &model.Group{ID: invite.GroupID})
+		if err != nil {
+			continue
+		}
+
+		group.Invites = slices.DeleteFunc(
+			group.Invites, func(a *model.Account) bool { return a.Email == invite.Email },
+		)
+		if err := db.Save(ctx, group); err != nil {
+			return err
+		}
+	}
+
+	tracing.Logger(ctx).Info("swept invitations", "expired", expired)
+	return nil
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestSweepInvitations(t *testing.T) {
+	defer db.Clear[*model.Group](t.Context())
+	defer db.Clear[*model.Invitation](t.Context())
+	env.Parse()
+
+	group := &model.Group{
+		ID:      1,
+		Name:    "Group 1",
+		Owner:   "owner@domain.se",
+		Invites: []*model.Account{{Email: "expired@domain.se"}, {Email: "pending@domain.se"}},
+	}
+	if err := db.Save(t.Context(), group); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+
+	now := time.Now().UnixMilli()
+	invites := []*model.Invitation{
+		{GroupID: 1, Email: "expired@domain.se", Expires: now - 1},
+		{GroupID: 1, Email: "pending@domain.se", Expires: now + 60000},
+		{GroupID: 1, Email: "legacy@domain.se"},
+	}
+	for _, invite := range invites {
+		if err := db.Save(t.Context(), invite); err != nil {
+			t.Fatalf("failed to create invitation: %v", err)
+		}
+	}
+
+	if err := sweepInvitations(t.Context()); err != nil {
+		t.Fatalf("failed to sweep invitations: %v", err)
+	}
+
+	if db.Exists(t.Context(), invites[0]) || !db.Exists(t.Context(), invites[1]) {
+		t.Error("expected only the expired invitation to be deleted")
+	}
+
+	legacy, err := db.Read(t.Context(), invites[2])
+	if err != nil || legacy.Expires <= now {
+		t.Errorf("expected legacy invitation to be given an expiry, got %+v", legacy)
+	}
+
+	group, err = db.Read(t.Context(), &model.Group{ID: 1})
+	if err != nil {
+		t.Fatalf("failed to read group: %v", err)
+	}
+	if len(group.Invites) != 1 || group.Invites[0].Email != "pending@domain.se" {
+		t.Errorf("expected only the pending invite left on the group, got %v", group.Invites)
+	}
+}
@@ -0,0 +1,293 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+)
+
+const departurePolicyTransfer = "transfer"
+
+// handleGroupTransfer offers the ownership of the group to one of its members,
+// the transfer is only made once the member accepts it.
+func handleGroupTransfer(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if existingGroup.Owner != email {
+		logger(r).Error(err, "user is not the owner of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	targetEmail := r.URL.Query().Get("email")
+	if targetEmail == "" {
+		logger(r).Error(err, "no email found in query parameters")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	member := existingGroup.Member(targetEmail)
+	if member == nil {
+		logger(r).Error(err, "group has no member with that email")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	existingGroup.PendingOwner = member.Email
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	event.Publish(r.Context(), &event.OwnershipOffered{
+		Time:  time.Now().UnixMilli(),
+		Group: existingGroup,
+		From:  &model.Account{Email: email},
+		To:    &model.Account{Email: member.Email, Tag: member.Tag},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGroupTransferCancel withdraws a pending ownership offer, either by the
+// owner that made it or by the member that declines it.
+func handleGroupTransferCancel(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if existingGroup.PendingOwner == "" {
+		logger(r).Error(err, "group has no pending ownership transfer")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if email != existingGroup.Owner && email != existingGroup.PendingOwner {
+		logger(r).Error(err, "user is not part of the ownership transfer")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	existingGroup.PendingOwner = ""
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGroupTransferAccept completes a pending ownership offer. The previous
+// owner stays in the group as an admin.
+func handleGroupTransferAccept(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	member := existingGroup.Member(email)
+	if existingGroup.PendingOwner != email || member == nil {
+		logger(r).Error(err, "user has not been offered the ownership of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	atLimit, err := ownsMaxGroups(r.Context(), email)
+	if err != nil {
+		logger(r).Error(err, "failed to count owned groups")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+	if atLimit {
+		logger(r).Error(err, "user has reached the owned group limit")
+		w.WriteHeader(http.StatusConflict)
+		w.Write(jsonGroupLimitErr)
+		return
+	}
+
+	previous := &model.Account{Email: existingGroup.Owner}
+	if account, err := db.Read(r.Context(), previous); err == nil {
+		previous.Tag = account.Tag
+	}
+
+	previousSettings := *existingGroup.SettingsOf(previous.Email)
+	existingGroup.TransferOwnership(email)
+	existingGroup.Members = append(existingGroup.Members, &model.Member{
+		Email:          previous.Email,
+		Tag:            previous.Tag,
+		Role:           model.RoleAdmin,
+		MemberSettings: previousSettings,
+	})
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	event.Publish(r.Context(), &event.OwnershipTransferred{
+		Time:  time.Now().UnixMilli(),
+		Group: existingGroup,
+		From:  previous,
+		To:    &model.Account{Email: member.Email, Tag: member.Tag},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// departOwner removes the owner from the group according to the owner
+// departure policy, either handing the group to the longest-standing member or
+// archiving it. The returned event is to be published once the group is saved.
+func departOwner(group *model.Group) event.Event {
+	now := time.Now().UnixMilli()
+	previous := &model.Account{Email: group.Owner}
+
+	if env.OwnerDeparturePolicy.Value() == departurePolicyTransfer {
+		if successor := group.LongestStandingMember(); successor != nil {
+			group.TransferOwnership(successor.Email)
+			return &event.OwnershipTransferred{
+				Time:  now,
+				Group: group,
+				From:  previous,
+				To:    &model.Account{Email: successor.Email, Tag: successor.Tag},
+			}
+		}
+	}
+
+	group.PendingOwner = ""
+	group.Archived = now
+	return &event.GroupArchived{Time: now, Group: group, By: previous}
+}
+
+// handleOwnerLeave lets the owner leave the group by applying the owner
+// departure policy.
+func handleOwnerLeave(w http.ResponseWriter, r *http.Request, group *model.Group) {
+	email := group.Owner
+	e := departOwner(group)
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), group); err != nil {
+		logger(r).Error(err, "saving the group to DB failed")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	cancelSchedules(r.Context(), group.ID, email)
+
+	event.Publish(r.Context(), e)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// departGroups removes the email from all groups it is part of, applying the
+// owner departure policy to the groups it owns.
+func departGroups(r *http.Request, email string) error {
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	groups, err := db.ReadAll[*model.Group](r.Context())
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		if !group.IsMember(email) && group.PendingOwner != email {
+			continue
+		}
+
+		var e event.Event
+		if group.Owner == email {
+			e = departOwner(group)
+		} else {
+			group.Members = slices.DeleteFunc(
+				group.Members, func(m *model.Member) bool { return m.Email == email },
+			)
+			if group.PendingOwner == email {
+				group.PendingOwner = ""
+			}
+		}
+
+		if err := db.Save(r.Context(), group); err != nil {
+			return err
+		}
+
+		cancelSchedules(r.Context(), group.ID, email)
+
+		if e != nil {
+			event.Publish(r.Context(), e)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestDepartOwner(t *testing.T) {
+	env.Parse()
+
+	group := &model.Group{
+		Owner:   "owner",
+		Members: []*model.Member{{Email: "second", Joined: 2}, {Email: "first", Joined: 1}},
+	}
+	if e, ok := departOwner(group).(*event.OwnershipTransferred); !ok || e.To.Email != "first" {
+		t.Fatalf("expected the ownership to be transferred to the longest-standing member")
+	}
+	if group.Owner != "first" || group.Archived != 0 {
+		t.Errorf("got owner %q archived %d", group.Owner, group.Archived)
+	}
+
+	group = &model.Group{Owner: "owner"}
+	if _, ok := departOwner(group).(*event.GroupArchived); !ok || group.Archived == 0 {
+		t.Errorf("expected a group without members to be archived")
+	}
+}
@@ -0,0 +1,79 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"net/http"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/model"
+)
+
+// readPreferences returns the notification preferences of the email, which
+// have no quiet hours for users that have never set them.
+func readPreferences(r *http.Request, email string) (*model.Preferences, error) {
+	prefs := &model.Preferences{Email: email}
+	if !db.Exists(r.Context(), prefs) {
+		return prefs, nil
+	}
+	return db.Read(r.Context(), prefs)
+}
+
+// handlePreferencesGet returns the notification preferences of the user.
+func handlePreferencesGet(w http.ResponseWriter, r *http.Request) {
+	prefs, err := readPreferences(r, getUserEmailFromToken(r))
+	if err != nil {
+		logger(r).Error(err, "failed to read preferences")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, prefs); err != nil {
+		logger(r).Error(err, "failed to serialize preferences")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+// handlePreferencesUpdate replaces the notification preferences of the user.
+// Pushes arriving during the quiet hours are delivered silently, dropped, or
+// deferred to a digest sent once the quiet hours end, depending on the mode.
+func handlePreferencesUpdate(w http.ResponseWriter, r *http.Request) {
+	prefs, err := model.Deserialize(r.Body, &model.Preferences{})
+	if err != nil {
+		logger(r).Error(err, "failed to deserialize the preferences")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonSerErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := prefs.Validate(); err != nil {
+		logger(r).Error(err, "preferences are invalid")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+	prefs.Email = getUserEmailFromToken(r)
+
+	db.AquireTableLock[*model.Preferences](r.Context())
+	defer db.ReleaseTableLock[*model.Preferences]()
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), prefs); err != nil {
+		logger(r).Error(err, "failed to save preferences")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, prefs); err != nil {
+		logger(r).Error(err, "failed to serialize preferences")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestPreferences(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Preferences](t.Context())
+	env.Parse()
+
+	token := loginAs(t, "sleepy@domain.se")
+
+	tests := []tc{
+		{name: "Quiet nights", body: `{"timezone":"Europe/Stockholm","quiet_start":"22:00","quiet_end":"07:00","quiet_mode":"digest"}`, wantStatus: 200},
+		{name: "Unknown timezone", body: `{"timezone":"Nowhere/Special"}`, wantStatus: 400},
+		{name: "Missing end", body: `{"quiet_start":"22:00"}`, wantStatus: 400},
+		{name: "Unknown mode", body: `{"quiet_start":"22:00","quiet_end":"07:00","quiet_mode":"loud"}`, wantStatus: 400},
+		{name: "Bad format", body: `{`, wantStatus: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/preferences", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", token)
+			recorder := httptest.NewRecorder()
+
+			handlePreferencesUpdate(recorder, req)
+
+			if recorder.Result().StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/preferences", nil)
+	req.Header.Set("Authorization", token)
+	recorder := httptest.NewRecorder()
+	handlePreferencesGet(recorder, req)
+
+	prefs, err := model.Deserialize(recorder.Body, &model.Preferences{})
+	if err != nil {
+		t.Fatalf("failed to deserialize preferences: %v", err)
+	}
+	if prefs.Email != "sleepy@domain.se" || prefs.QuietMode != model.QuietDigest ||
+		prefs.Timezone != "Europe/Stockholm" {
+		t.Errorf("preferences were not saved, got %+v", prefs)
+	}
+}
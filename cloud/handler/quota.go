@@ -0,0 +1,147 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+var jsonMemberLimitErr = []byte(`{"error": "member limit reached"}`)
+var jsonGroupLimitErr = []byte(`{"error": "owned group limit reached"}`)
+var jsonInviteLimitErr = []byte(`{"error": "pending invite limit reached"}`)
+
+// quotaFor returns the limits of the account, the defaults fill in anything an
+// admin has not overridden.
+func quotaFor(ctx context.Context, email string) *model.Quota {
+	quota := &model.Quota{Email: email}
+	if override, err := db.Read(ctx, quota); err == nil {
+		quota = override
+	}
+
+	if quota.MaxGroupMembers == 0 {
+		quota.MaxGroupMembers = env.MaxGroupMembers.Value()
+	}
+	if quota.MaxOwnedGroups == 0 {
+		quota.MaxOwnedGroups = env.MaxOwnedGroups.Value()
+	}
+	if quota.MaxPendingInvites == 0 {
+		quota.MaxPendingInvites = env.MaxPendingInvites.Value()
+	}
+	return quota
+}
+
+// groupFull returns true if the group can not take on more members, counting
+// the owner and the additional pending members. The quota of the owner
+// applies.
+func groupFull(ctx context.Context, group *model.Group, pending int) bool {
+	return len(group.Members)+1+pending >= quotaFor(ctx, group.Owner).MaxGroupMembers
+}
+
+// ownsMaxGroups returns true if the account can not own any more groups.
+// Archived groups do not count.
+func ownsMaxGroups(ctx context.Context, email string) (bool, error) {
+	groups, err := db.ReadAll[*model.Group](ctx)
+	if err != nil {
+		return false, err
+	}
+
+	owned := 0
+	for _, group := range groups {
+		if group.Owner == email && group.Archived == 0 {
+			owned++
+		}
+	}
+	return owned >= quotaFor(ctx, email).MaxOwnedGroups, nil
+}
+
+// sentMaxInvites returns true if the account can not send any more invitations
+// until some of its pending ones are answered or expire.
+func sentMaxInvites(ctx context.Context, email string) (bool, error) {
+	invites, err := db.ReadAll[*model.Invitation](ctx)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now().UnixMilli()
+	pending := 0
+	for _, invite := range invites {
+		if invite.InvitedBy == email && !invite.Expired(now) {
+			pending++
+		}
+	}
+	return pending >= quotaFor(ctx, email).MaxPendingInvites, nil
+}
+
+func handleQuotaGet(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Path[len("/admin/quotas/"):]
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, quotaFor(r.Context(), email)); err != nil {
+		logger(r).Error(err, "failed to serialize quota")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+// handleQuotaUpdate overrides the limits of an account, zero values restore
+// the defaults.
+func handleQuotaUpdate(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Path[len("/admin/quotas/"):]
+
+	quota, err := model.Deserialize(r.Body, &model.Quota{})
+	if err != nil {
+		logger(r).Error(err, "failed to deserialize quota")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonSerErr)
+		return
+	}
+
+	if quota.MaxGroupMembers < 0 || quota.MaxOwnedGroups < 0 || quota.MaxPendingInvites < 0 {
+		logger(r).Error(err, "quota limits can not be negative")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+	quota.Email = email
+
+	db.AquireTableLock[*model.Quota](r.Context())
+	defer db.ReleaseTableLock[*model.Quota]()
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), quota); err != nil {
+		logger(r).Error(err, "failed to save quota to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, quotaFor(r.Context(), email)); err != nil {
+		logger(r).Error(err, "failed to serialize quota")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+func handleQuotaDelete(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Path[len("/admin/quotas/"):]
+
+	db.AquireTableLock[*model.Quota](r.Context())
+	defer db.ReleaseTableLock[*model.Quota]()
+
+	//nolint:gosec,govet
+	if err := db.Delete(r.Context(), &model.Quota{Email: email}); err != nil {
+		logger(r).Error(err, "no quota override found for the account")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestQuotaFor(t *testing.T) {
+	defer db.Clear[*model.Quota](t.Context())
+	env.Parse()
+
+	if err := db.Save(t.Context(), &model.Quota{Email: "vip@domain.se", MaxGroupMembers: 2}); err != nil {
+		t.Fatalf("failed to create quota: %v", err)
+	}
+
+	quota := quotaFor(t.Context(), "vip@domain.se")
+	if quota.MaxGroupMembers != 2 || quota.MaxOwnedGroups != env.MaxOwnedGroups.Value() {
+		t.Errorf("expected overridden member limit and default group limit, got %+v", quota)
+	}
+
+	group := &model.Group{Owner: "vip@domain.se", Members: []*model.Member{{Email: "member"}}}
+	if !groupFull(t.Context(), group, 0) {
+		t.Error("expected the group to be full with the owner and one member")
+	}
+
+	group.Owner = "regular@domain.se"
+	if groupFull(t.Context(), group, 0) {
+		t.Error("expected the default member limit to apply")
+	}
+}
+
+func TestGroupCreateLimit(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	defer db.Clear[*model.Quota](t.Context())
+	env.Parse()
+
+	token := loginAs(t, "email@domain.se")
+	if err := db.Save(t.Context(), &model.Quota{Email: "email@domain.se", MaxOwnedGroups: 1}); err != nil {
+		t.Fatalf("failed to create quota: %v", err)
+	}
+
+	for _, wantStatus := range []int{201, 409} {
+		req := httptest.NewRequest("POST", "/groups", strings.NewReader(`{"name":"My Group"}`))
+		req.Header.Set("Authorization", token)
+		recorder := httptest.NewRecorder()
+
+		handleGroupCreate(recorder, req)
+
+		if recorder.Code != wantStatus {
+			t.Errorf("got status %d, want %d", recorder.Code, wantStatus)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+const (
+	maxTappCooldown  = 3600
+	maxTappRateLimit = 600
+	tappRateWindow   = time.Minute
+)
+
+// tappLimiter enforces the tapp cooldown of each member and the tapp rate limit
+// of each group. The state is kept in memory only, a restart resets it.
+type tappLimiter struct {
+	lock sync.Mutex
+	// last holds the time of the latest tapp of each member, keyed by group
+	// and email.
+	last map[string]time.Time
+	// recent holds the times of the tapps of each group within the rate
+	// window, oldest first.
+	recent map[int][]time.Time
+	// pruned is the time the state was last pruned of tapps too old to
+	// limit anything.
+	pruned time.Time
+}
+
+//nolint:gochecknoglobals
+var limiter = newTappLimiter()
+
+func newTappLimiter() *tappLimiter {
+	return &tappLimiter{last: map[string]time.Time{}, recent: map[int][]time.Time{}}
+}
+
+func tappCooldown(group *model.Group) time.Duration {
+	if group.TappCooldown != 0 {
+		return time.Duration(group.TappCooldown) * time.Second
+	}
+	return time.Duration(env.TappCooldownSeconds.Value()) * time.Second
+}
+
+func tappRateLimit(group *model.Group) int {
+	if group.TappRateLimit != 0 {
+		return group.TappRateLimit
+	}
+	return env.TappRatePerMinute.Value()
+}
+
+// validTappLimits returns true if the tapp limits of the group are within
+// bounds, zero values keep the server defaults.
+func validTappLimits(group *model.Group) bool {
+	return group.TappCooldown >= 0 && group.TappCooldown <= maxTappCooldown &&
+		group.TappRateLimit >= 0 && group.TappRateLimit <= maxTappRateLimit
+}
+
+// allow returns zero if the email may tapp the group at the time now, or the
+// time left until it may. The tapp is counted once it is made, see record, the
+// caller holds the tapp lock of the group in between.
+func (l *tappLimiter) allow(group *model.Group, email string, now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	key := fmt.Sprintf("%d-%s", group.ID, email)
+	if last, ok := l.last[key]; ok {
+		if wait := last.Add(tappCooldown(group)).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	recent := l.recent[group.ID]
+	for len(recent) > 0 && now.Sub(recent[0]) >= tappRateWindow {
+		recent = recent[1:]
+	}
+	l.recent[group.ID] = recent
+	if limit := tappRateLimit(group); len(recent) >= limit {
+		return recent[len(recent)-limit].Add(tappRateWindow).Sub(now)
+	}
+	return 0
+}
+
+// record counts a tapp of the email in the group made at the time now.
+func (l *tappLimiter) record(group *model.Group, email string, now time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.last[fmt.Sprintf("%d-%s", group.ID, email)] = now
+	l.recent[group.ID] = append(l.recent[group.ID], now)
+
+	if now.Sub(l.pruned) >= tappRateWindow {
+		l.prune(now)
+	}
+}
+
+// prune forgets the tapps older than any cooldown and the rate window, the
+// caller holds the lock.
+func (l *tappLimiter) prune(now time.Time) {
+	l.pruned = now
+
+	cooldown := max(maxTappCooldown*time.Second, tappCooldown(&model.Group{}))
+	maps.DeleteFunc(l.last, func(_ string, last time.Time) bool {
+		return now.Sub(last) >= cooldown
+	})
+	maps.DeleteFunc(l.recent, func(_ int, recent []time.Time) bool {
+		return len(recent) == 0 || now.Sub(recent[len(recent)-1]) >= tappRateWindow
+	})
+}
+
+// writeTooManyTapps responds with a 429, telling the client how long to wait
+// both in the Retry-After header and the body.
+func writeTooManyTapps(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	fmt.Fprintf(w, `{"error": "tapping too fast", "retry_after_ms": %d}`, wait.Milliseconds())
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestTappLimiter(t *testing.T) {
+	env.Parse()
+
+	l := newTappLimiter()
+	group := &model.Group{ID: 1, TappCooldown: 10, TappRateLimit: 2}
+	now := time.Now()
+
+	tapp := func(group *model.Group, email string, at time.Time) time.Duration {
+		wait := l.allow(group, email, at)
+		if wait == 0 {
+			l.record(group, email, at)
+		}
+		return wait
+	}
+
+	if wait := l.allow(group, "alice", now); wait != 0 {
+		t.Fatalf("first tapp waits %s", wait)
+	}
+	if wait := tapp(group, "alice", now); wait != 0 {
+		t.Fatalf("a tapp that was not made should not count, waits %s", wait)
+	}
+	if wait := tapp(group, "alice", now.Add(4*time.Second)); wait != 6*time.Second {
+		t.Errorf("got cooldown %s, want %s", wait, 6*time.Second)
+	}
+	if wait := tapp(group, "bob", now.Add(5*time.Second)); wait != 0 {
+		t.Errorf("other members should not share the cooldown, waits %s", wait)
+	}
+	if wait := tapp(group, "carol", now.Add(20*time.Second)); wait != 40*time.Second {
+		t.Errorf("got group wait %s, want %s", wait, 40*time.Second)
+	}
+	if wait := tapp(group, "carol", now.Add(time.Minute)); wait != 0 {
+		t.Errorf("rate window should have moved on, waits %s", wait)
+	}
+	if wait := tapp(&model.Group{ID: 2}, "alice", now); wait != 0 {
+		t.Errorf("groups should not share limits, waits %s", wait)
+	}
+}
+
+func TestTappLimiterPrune(t *testing.T) {
+	env.Parse()
+
+	l := newTappLimiter()
+	now := time.Now()
+
+	l.record(&model.Group{ID: 1}, "alice", now)
+	l.record(&model.Group{ID: 2}, "bob", now.Add(maxTappCooldown*time.Second/2))
+	l.record(&model.Group{ID: 3}, "carol", now.Add(maxTappCooldown*time.Second))
+
+	if len(l.last) != 2 {
+		t.Errorf("got %d cooldowns, want 2", len(l.last))
+	}
+	if len(l.recent) != 1 {
+		t.Errorf("got %d rate windows, want 1", len(l.recent))
+	}
+}
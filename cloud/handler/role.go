@@ -0,0 +1,89 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func handleGroupPromote(w http.ResponseWriter, r *http.Request) {
+	handleGroupRoleChange(w, r, model.RoleAdmin)
+}
+
+func handleGroupDemote(w http.ResponseWriter, r *http.Request) {
+	handleGroupRoleChange(w, r, model.RoleMember)
+}
+
+func handleGroupRoleChange(w http.ResponseWriter, r *http.Request, role model.Role) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if !existingGroup.Can(email, model.PermissionManageRoles) {
+		logger(r).Error(err, "user is not allowed to manage roles in the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	targetEmail := r.URL.Query().Get("email")
+	if targetEmail == "" {
+		logger(r).Error(err, "no email found in query parameters")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	member := existingGroup.Member(targetEmail)
+	if member == nil {
+		logger(r).Error(err, "group has no member with that email")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if existingGroup.RoleOf(targetEmail) == role {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	member.Role = role
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	event.Publish(r.Context(), &event.MemberRoleChanged{
+		Time:    time.Now().UnixMilli(),
+		Group:   existingGroup,
+		Account: &model.Account{Email: member.Email, Tag: member.Tag},
+		Role:    role,
+		By:      &model.Account{Email: email},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,397 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/tapp-backend/tracing"
+)
+
+const (
+	scheduleInterval = 30 * time.Second
+	// maxSchedules is the number of schedules a member can have in a group.
+	maxSchedules = 10
+)
+
+var jsonScheduleLimitErr = []byte(`{"error": "schedule limit reached"}`)
+
+// RunSchedules makes the scheduled tapps that are due, until the context is
+// done. Schedules are stored, so they survive restarts. Runs missed while the
+// server was down are made once on start.
+func RunSchedules(ctx context.Context) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		//nolint:gosec,govet
+		if err := runSchedules(ctx, time.Now()); err != nil {
+			tracing.Logger(ctx).Error(err, "failed to run schedules")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runSchedules makes the tapps of the schedules due at now and moves them on to
+// their next run, deleting the ones that will not run again.
+func runSchedules(ctx context.Context, now time.Time) error {
+	ctx, span := tracer.Start(ctx, "run schedules")
+	defer span.End()
+
+	db.AquireTableLock[*model.Schedule](ctx)
+	defer db.ReleaseTableLock[*model.Schedule]()
+
+	schedules, err := db.ReadAll[*model.Schedule](ctx)
+	if err != nil {
+		return err
+	}
+
+	ran := 0
+	for _, schedule := range schedules {
+		if schedule.Next == 0 || schedule.Next > now.UnixMilli() {
+			continue
+		}
+
+		switch err := runSchedule(ctx, schedule, now); {
+		case errors.Is(err, errScheduleOrphaned):
+			tracing.Logger(ctx).Info("cancelling orphaned schedule", "schedule", schedule.ID)
+			_ = db.Delete(ctx, schedule)
+			continue
+		case errors.Is(err, errScheduleSkipped):
+			tracing.Logger(ctx).Info("skipping schedule run", "schedule", schedule.ID)
+		case err != nil:
+			tracing.Logger(ctx).Error(err, "failed to run schedule", "schedule", schedule.ID)
+		default:
+			ran++
+		}
+
+		schedule.LastRun = now.UnixMilli()
+		schedule.Next = schedule.NextRun(now)
+		if schedule.Next == 0 {
+			_ = db.Delete(ctx, schedule)
+			continue
+		}
+		if err := db.Save(ctx, schedule); err != nil {
+			return err
+		}
+	}
+
+	tracing.Logger(ctx).Info("ran schedules", "ran", ran)
+	return nil
+}
+
+var (
+	errScheduleOrphaned = errors.New("schedule owner is no longer in the group")
+	errScheduleSkipped  = errors.New("schedule run skipped")
+)
+
+// runSchedule makes the tapp of the schedule, unless the group is archived or
+// the member is tapping too fast.
+func runSchedule(ctx context.Context, schedule *model.Schedule, now time.Time) error {
+	group, err := db.Read(ctx, &model.Group{ID: schedule.GroupID})
+	if err != nil || !group.IsMember(schedule.Email) {
+		return errScheduleOrphaned
+	}
+	if group.Archived != 0 {
+		return errScheduleSkipped
+	}
+
+	account, err := db.Read(ctx, &model.Account{Email: schedule.Email})
+	if err != nil {
+		return errScheduleOrphaned
+	}
+
+	if wait := limiter.allow(group, schedule.Email, now); wait > 0 {
+		return errScheduleSkipped
+	}
+
+	newTapp := schedule.Tapp()
+	newTapp.GroupID = group.ID
+	newTapp.Time = now.UnixMilli()
+	newTapp.User = &model.Account{Email: account.Email, Tag: account.Tag}
+	newTapp.Type = newTapp.TypeOrDefault()
+
+	db.SimpleAcquire(ctx, newTapp)
+	defer db.SimpleRelease(newTapp)
+
+	tapps, err := db.SimpleRead(ctx, newTapp)
+	if err != nil {
+		return err
+	}
+	newTapp.ID = len(tapps) + 1
+
+	if err := db.SimpleAppend(ctx, newTapp); err != nil {
+		return err
+	}
+	limiter.record(group, schedule.Email, now)
+
+	event.Publish(ctx, &event.TappCreated{Group: group, Tapp: newTapp})
+	return nil
+}
+
+// cancelSchedules deletes the schedules of the email in the group, once it is
+// no longer a member. Schedules that are missed are cancelled when due.
+func cancelSchedules(ctx context.Context, groupID int, email string) {
+	db.AquireTableLock[*model.Schedule](ctx)
+	defer db.ReleaseTableLock[*model.Schedule]()
+
+	schedules, err := db.ReadAll[*model.Schedule](ctx)
+	if err != nil {
+		tracing.Logger(ctx).Error(err, "failed to read schedules")
+		return
+	}
+
+	for _, schedule := range schedules {
+		if schedule.GroupID == groupID && schedule.Email == email {
+			_ = db.Delete(ctx, schedule)
+		}
+	}
+}
+
+// readSchedule returns the schedule in the path, as long as it belongs to the
+// user and the group in the path.
+func readSchedule(r *http.Request) (*model.Schedule, int, error) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	groupID, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	scheduleID, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	schedule, err := db.Read(r.Context(), &model.Schedule{ID: scheduleID})
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	if schedule.GroupID != groupID || schedule.Email != getUserEmailFromToken(r) {
+		return nil, http.StatusNotFound, errors.New("schedule belongs to someone else")
+	}
+	return schedule, http.StatusOK, nil
+}
+
+// handleScheduleCreate schedules a tapp of the group for the user, either once
+// at a time or recurring by a cron expression.
+func handleScheduleCreate(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	newSchedule, err := model.Deserialize(r.Body, &model.Schedule{})
+	if err != nil {
+		logger(r).Error(err, "failed to deserialize schedule")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonSerErr)
+		return
+	}
+
+	now := time.Now()
+	newSchedule.Message = strings.TrimSpace(newSchedule.Message)
+
+	//nolint:gosec,govet
+	if err := newSchedule.Validate(now.UnixMilli()); err != nil {
+		logger(r).Error(err, "schedule is invalid")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	group, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if !group.IsMember(email) {
+		logger(r).Error(err, "user is not a member of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	db.AquireTableLock[*model.Schedule](r.Context())
+	defer db.ReleaseTableLock[*model.Schedule]()
+
+	schedules, err := db.ReadAll[*model.Schedule](r.Context())
+	if err != nil {
+		logger(r).Error(err, "failed to read schedules")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	// IDs are not taken from db.NextID, schedules are deleted and the table
+	// length does not give a free ID.
+	count := 0
+	newSchedule.ID = 1
+	for _, schedule := range schedules {
+		newSchedule.ID = max(newSchedule.ID, schedule.ID+1)
+		if schedule.GroupID == group.ID && schedule.Email == email {
+			count++
+		}
+	}
+	if count >= maxSchedules {
+		logger(r).Error(err, "user has reached the schedule limit")
+		w.WriteHeader(http.StatusConflict)
+		w.Write(jsonScheduleLimitErr)
+		return
+	}
+
+	newSchedule.GroupID = group.ID
+	newSchedule.Email = email
+	newSchedule.Created = now.UnixMilli()
+	newSchedule.LastRun = 0
+	newSchedule.Next = newSchedule.NextRun(now)
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), newSchedule); err != nil {
+		logger(r).Error(err, "failed to save schedule to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, newSchedule); err != nil {
+		logger(r).Error(err, "failed to write schedule to response body")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+// handleScheduleList lists the schedules of the user in the group.
+func handleScheduleList(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	schedules, err := db.ReadAll[*model.Schedule](r.Context())
+	if err != nil {
+		logger(r).Error(err, "failed to read schedules")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	own := []*model.Schedule{}
+	for _, schedule := range schedules {
+		if schedule.GroupID == i && schedule.Email == email {
+			own = append(own, schedule)
+		}
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, own); err != nil {
+		logger(r).Error(err, "failed to serialize schedules")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+// handleScheduleUpdate replaces the tapp and timing of a schedule of the user.
+func handleScheduleUpdate(w http.ResponseWriter, r *http.Request) {
+	updatedSchedule, err := model.Deserialize(r.Body, &model.Schedule{})
+	if err != nil {
+		logger(r).Error(err, "failed to deserialize schedule")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonSerErr)
+		return
+	}
+
+	now := time.Now()
+	updatedSchedule.Message = strings.TrimSpace(updatedSchedule.Message)
+
+	//nolint:gosec,govet
+	if err := updatedSchedule.Validate(now.UnixMilli()); err != nil {
+		logger(r).Error(err, "schedule is invalid")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Schedule](r.Context())
+	defer db.ReleaseTableLock[*model.Schedule]()
+
+	existingSchedule, status, err := readSchedule(r)
+	if err != nil {
+		logger(r).Error(err, "schedule not found")
+		w.WriteHeader(status)
+		return
+	}
+
+	updatedSchedule.ID = existingSchedule.ID
+	updatedSchedule.GroupID = existingSchedule.GroupID
+	updatedSchedule.Email = existingSchedule.Email
+	updatedSchedule.Created = existingSchedule.Created
+	updatedSchedule.LastRun = existingSchedule.LastRun
+	updatedSchedule.Next = updatedSchedule.NextRun(now)
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), updatedSchedule); err != nil {
+		logger(r).Error(err, "failed to save schedule to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, updatedSchedule); err != nil {
+		logger(r).Error(err, "failed to write schedule to response body")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+func handleScheduleDelete(w http.ResponseWriter, r *http.Request) {
+	db.AquireTableLock[*model.Schedule](r.Context())
+	defer db.ReleaseTableLock[*model.Schedule]()
+
+	existingSchedule, status, err := readSchedule(r)
+	if err != nil {
+		logger(r).Error(err, "schedule not found")
+		w.WriteHeader(status)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := db.Delete(r.Context(), existingSchedule); err != nil {
+		logger(r).Error(err, "failed to delete schedule from DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
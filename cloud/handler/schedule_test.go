@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestSchedules(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	defer db.Clear[*model.Schedule](t.Context())
+	defer db.SimpleClear(t.Context(), &model.Tapp{GroupID: 1})
+	env.Parse()
+	limiter = newTappLimiter()
+
+	tokens := map[string]string{
+		"alice@domain.se": loginAs(t, "alice@domain.se"),
+		"bob@domain.se":   loginAs(t, "bob@domain.se"),
+	}
+	saveGroup(t, "alice@domain.se", "bob@domain.se")
+
+	soon := strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10)
+	tests := []tc{
+		{name: "Once", token: tokens["bob@domain.se"], body: `{"at":` + soon + `,"type":"urgent"}`, wantStatus: 201},
+		{name: "Weekdays", token: tokens["alice@domain.se"], body: `{"cron":"0 8 * * 1-5","timezone":"Europe/Stockholm"}`, wantStatus: 201},
+		{name: "Past", token: tokens["bob@domain.se"], body: `{"at":1000}`, wantStatus: 400},
+		{name: "Bad cron", token: tokens["bob@domain.se"], body: `{"cron":"0 25 * * *"}`, wantStatus: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/groups/1/schedules", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.token)
+			recorder := httptest.NewRecorder()
+
+			handleScheduleCreate(recorder, req)
+
+			if recorder.Result().StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("DELETE", "/groups/1/schedules/2", nil)
+	req.Header.Set("Authorization", tokens["bob@domain.se"])
+	recorder := httptest.NewRecorder()
+	handleScheduleDelete(recorder, req)
+	if recorder.Code != 404 {
+		t.Errorf("got status %d deleting the schedule of someone else, want %d", recorder.Code, 404)
+	}
+
+	// The one-off schedule runs once and is done, the recurring one moves on.
+	if err := runSchedules(t.Context(), time.Now().Add(2*time.Hour)); err != nil {
+		t.Fatalf("failed to run schedules: %v", err)
+	}
+
+	tapps, err := db.SimpleRead(t.Context(), &model.Tapp{GroupID: 1})
+	if err != nil {
+		t.Fatalf("failed to read tapps: %v", err)
+	}
+	if len(tapps) != 1 || tapps[0].User.Email != "bob@domain.se" || tapps[0].Type != model.TappTypeUrgent {
+		t.Errorf("unexpected scheduled tapps: %+v", tapps)
+	}
+
+	schedules, err := db.ReadAll[*model.Schedule](t.Context())
+	if err != nil || len(schedules) != 1 || schedules[0].Email != "alice@domain.se" {
+		t.Fatalf("unexpected schedules after the run: %+v, %v", schedules, err)
+	}
+
+	cancelSchedules(t.Context(), 1, "alice@domain.se")
+	if schedules, _ := db.ReadAll[*model.Schedule](t.Context()); len(schedules) != 0 {
+		t.Errorf("got %d schedules after leaving, want 0", len(schedules))
+	}
+}
@@ -0,0 +1,84 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/model"
+)
+
+var regexNickname = regexp.MustCompile(`^[\p{L}\p{N} _-]{1,30}$`)
+
+// handleGroupSettingsUpdate replaces the settings of the user in the group. An
+// empty nickname removes it, a muted until in the past unmutes.
+func handleGroupSettingsUpdate(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	updatedSettings, err := model.Deserialize(r.Body, &model.MemberSettings{})
+	if err != nil {
+		logger(r).Error(err, "failed to deserialize the settings")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonSerErr)
+		return
+	}
+
+	updatedSettings.Nickname = strings.TrimSpace(updatedSettings.Nickname)
+	if (updatedSettings.Nickname != "" && !regexNickname.MatchString(updatedSettings.Nickname)) ||
+		!updatedSettings.Notify.Valid() || updatedSettings.MutedUntil < 0 {
+		logger(r).Error(err, "settings are invalid")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	db.AquireTableLock[*model.Group](r.Context())
+	defer db.ReleaseTableLock[*model.Group]()
+
+	existingGroup, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if existingGroup.Owner == email && existingGroup.OwnerSettings == nil {
+		existingGroup.OwnerSettings = &model.MemberSettings{}
+	}
+
+	settings := existingGroup.SettingsOf(email)
+	if settings == nil {
+		logger(r).Error(err, "user is not a member of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	*settings = *updatedSettings
+
+	//nolint:gosec,govet
+	if err := db.Save(r.Context(), existingGroup); err != nil {
+		logger(r).Error(err, "failed to save group to DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, settings); err != nil {
+		logger(r).Error(err, "failed to write settings to response body")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestGroupSettingsUpdate(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	env.Parse()
+
+	tokens := map[string]string{
+		"owner@domain.se":    loginAs(t, "owner@domain.se"),
+		"member@domain.se":   loginAs(t, "member@domain.se"),
+		"stranger@domain.se": loginAs(t, "stranger@domain.se"),
+	}
+	saveGroup(t, "owner@domain.se", "member@domain.se")
+
+	tests := []tc{
+		{name: "Owner nickname", token: tokens["owner@domain.se"], body: `{"nickname":"Boss"}`, wantStatus: 200},
+		{name: "Member mute", token: tokens["member@domain.se"], body: `{"muted_until":4102444800000,"notify":"mentions"}`, wantStatus: 200},
+		{name: "Unknown level", token: tokens["member@domain.se"], body: `{"notify":"sometimes"}`, wantStatus: 400},
+		{name: "Invalid nickname", token: tokens["member@domain.se"], body: `{"nickname":"<script>"}`, wantStatus: 400},
+		{name: "Stranger", token: tokens["stranger@domain.se"], body: `{"nickname":"Spy"}`, wantStatus: 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/groups/1/me", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.token)
+			recorder := httptest.NewRecorder()
+
+			handleGroupSettingsUpdate(recorder, req)
+
+			if recorder.Result().StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	group, err := db.Read(t.Context(), &model.Group{ID: 1})
+	if err != nil {
+		t.Fatalf("failed to read group: %v", err)
+	}
+	if group.OwnerSettings.Nickname != "Boss" || group.Members[0].Notify != model.NotifyMentions ||
+		group.Members[0].MutedUntil == 0 {
+		t.Errorf("settings were not saved, got %+v and %+v", group.OwnerSettings, group.Members[0])
+	}
+}
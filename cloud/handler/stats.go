@@ -0,0 +1,140 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/tapp-backend/tracing"
+)
+
+// readStats returns the statistics of the group. Groups that have none yet,
+// like groups tapped before statistics were kept, get theirs built from the
+// tapp log once. The caller must hold the stats table lock.
+func readStats(ctx context.Context, groupID int) (*model.GroupStats, bool, error) {
+	stats := &model.GroupStats{GroupID: groupID}
+	if db.Exists(ctx, stats) {
+		stats, err := db.Read(ctx, stats)
+		return stats, false, err
+	}
+
+	tapps, err := db.SimpleRead(ctx, &model.Tapp{GroupID: groupID})
+	if err != nil {
+		return nil, false, err
+	}
+	model.NumberTapps(tapps)
+
+	for _, tapp := range tapps {
+		var original *model.Tapp
+		if tapp.ReplyTo > 0 && tapp.ReplyTo <= len(tapps) {
+			original = tapps[tapp.ReplyTo-1]
+		}
+		stats.Record(tapp, original)
+		stats.BuiltThrough = max(stats.BuiltThrough, tapp.ID)
+	}
+	return stats, true, nil
+}
+
+// recordTappStats adds a new tapp to the statistics of its group.
+func recordTappStats(ctx context.Context, e *event.TappCreated) {
+	db.AquireTableLock[*model.GroupStats](ctx)
+	defer db.ReleaseTableLock[*model.GroupStats]()
+
+	stats, _, err := readStats(ctx, e.Group.ID)
+	if err != nil {
+		tracing.Logger(ctx).Error(err, "failed to read group stats", "group", e.Group.ID)
+		return
+	}
+	// Statistics built from the log already hold the tapps in it, this one
+	// and any made before the build. Only the build is compared against, the
+	// events of later tapps may arrive in any order.
+	if e.Tapp.ID > stats.BuiltThrough {
+		stats.Record(e.Tapp, e.Original)
+	}
+
+	//nolint:gosec,govet
+	if err := db.Save(ctx, stats); err != nil {
+		tracing.Logger(ctx).Error(err, "failed to save group stats", "group", e.Group.ID)
+	}
+}
+
+// handleGroupStats shows the leaderboard of the group for the window query
+// parameter, day, week or month, week unless set.
+func handleGroupStats(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	window := model.StatsWindow(r.URL.Query().Get("window"))
+	if window == "" {
+		window = model.StatsWeek
+	}
+	if !window.Valid() {
+		logger(r).Info("unknown stats window", "window", window)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	group, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !group.IsMember(getUserEmailFromToken(r)) {
+		logger(r).Error(err, "user is not a member of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	db.AquireTableLock[*model.GroupStats](r.Context())
+	defer db.ReleaseTableLock[*model.GroupStats]()
+
+	stats, built, err := readStats(r.Context(), group.ID)
+	if err != nil {
+		logger(r).Error(err, "failed to read group stats")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	if built {
+		//nolint:gosec,govet
+		if err := db.Save(r.Context(), stats); err != nil {
+			logger(r).Error(err, "failed to save group stats to DB")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(jsonDBErr)
+			return
+		}
+	}
+
+	response := struct {
+		Window      model.StatsWindow         `json:"window"`
+		Leaderboard []*model.LeaderboardEntry `json:"leaderboard"`
+	}{
+		Window:      window,
+		Leaderboard: stats.Leaderboard(group, window, time.Now()),
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, response); err != nil {
+		logger(r).Error(err, "failed to serialize group stats")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
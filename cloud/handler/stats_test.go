@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestGroupStats(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	defer db.Clear[*model.GroupStats](t.Context())
+	defer db.SimpleClear(t.Context(), &model.Tapp{GroupID: 1})
+	env.Parse()
+
+	token := loginAs(t, "alice@domain.se")
+	group := saveGroup(t, "alice@domain.se", "bob@domain.se")
+
+	now := time.Now().UnixMilli()
+	tapps := []*model.Tapp{
+		{ID: 1, Time: now - 2000, GroupID: 1, User: &model.Account{Email: "bob@domain.se"}},
+		{ID: 2, Time: now - 1000, GroupID: 1, User: &model.Account{Email: "alice@domain.se"}, ReplyTo: 1},
+	}
+	// The first tapp predates the statistics, the second is recorded as it is
+	// made.
+	if err := db.SimpleAppend(t.Context(), tapps[0]); err != nil {
+		t.Fatalf("failed to create tapp: %v", err)
+	}
+	recordTappStats(t.Context(), &event.TappCreated{Group: group, Tapp: tapps[0]})
+	if err := db.SimpleAppend(t.Context(), tapps[1]); err != nil {
+		t.Fatalf("failed to create tapp: %v", err)
+	}
+	recordTappStats(t.Context(), &event.TappCreated{Group: group, Tapp: tapps[1], Original: tapps[0]})
+
+	req := httptest.NewRequest("GET", "/groups/1/stats?window=fortnight", nil)
+	req.Header.Set("Authorization", token)
+	recorder := httptest.NewRecorder()
+	handleGroupStats(recorder, req)
+	if recorder.Code != 400 {
+		t.Errorf("got status %d for an unknown window, want %d", recorder.Code, 400)
+	}
+
+	req = httptest.NewRequest("GET", "/groups/1/stats?window=week", nil)
+	req.Header.Set("Authorization", token)
+	recorder = httptest.NewRecorder()
+	handleGroupStats(recorder, req)
+	if recorder.Code != 200 {
+		t.Fatalf("got status %d, want %d", recorder.Code, 200)
+	}
+
+	response := struct {
+		Leaderboard []*model.LeaderboardEntry `json:"leaderboard"`
+	}{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal stats: %v", err)
+	}
+
+	board := response.Leaderboard
+	if len(board) != 2 || board[0].Tapps != 1 || board[1].Tapps != 1 {
+		t.Fatalf("unexpected leaderboard: %+v", board)
+	}
+	for _, entry := range board {
+		if entry.Email == "alice@domain.se" && (entry.Replies != 1 || entry.AvgResponseTime != 1000) {
+			t.Errorf("unexpected reply stats: %+v", entry)
+		}
+	}
+}
+
+func TestRecordTappStatsBuiltFromLog(t *testing.T) {
+	defer db.Clear[*model.GroupStats](t.Context())
+	defer db.SimpleClear(t.Context(), &model.Tapp{GroupID: 1})
+
+	group := &model.Group{ID: 1, Name: "Group 1", Owner: "alice@domain.se"}
+	now := time.Now().UnixMilli()
+	tapps := []*model.Tapp{
+		{ID: 1, Time: now - 3000, GroupID: 1, User: &model.Account{Email: "alice@domain.se"}},
+		{ID: 2, Time: now - 2000, GroupID: 1, User: &model.Account{Email: "alice@domain.se"}},
+		{ID: 3, Time: now - 1000, GroupID: 1, User: &model.Account{Email: "alice@domain.se"}},
+	}
+
+	// Both tapps are in the log when the first event builds the statistics,
+	// the event of the second must not count it again.
+	for _, tapp := range tapps[:2] {
+		if err := db.SimpleAppend(t.Context(), tapp); err != nil {
+			t.Fatalf("failed to create tapp: %v", err)
+		}
+	}
+	recordTappStats(t.Context(), &event.TappCreated{Group: group, Tapp: tapps[0]})
+	recordTappStats(t.Context(), &event.TappCreated{Group: group, Tapp: tapps[1]})
+
+	if err := db.SimpleAppend(t.Context(), tapps[2]); err != nil {
+		t.Fatalf("failed to create tapp: %v", err)
+	}
+	recordTappStats(t.Context(), &event.TappCreated{Group: group, Tapp: tapps[2]})
+
+	stats, err := db.Read(t.Context(), &model.GroupStats{GroupID: 1})
+	if err != nil {
+		t.Fatalf("failed to read group stats: %v", err)
+	}
+	if total := stats.Members["alice@domain.se"].Total; total != 3 {
+		t.Errorf("got %d tapps, want %d", total, 3)
+	}
+}
@@ -0,0 +1,163 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+	"github.com/trebent/zerologr"
+)
+
+const (
+	streamBuffer    = 16
+	streamHeartbeat = 15 * time.Second
+)
+
+//nolint:gochecknoglobals
+var (
+	streamLock = sync.Mutex{}
+	streams    = map[int]map[chan event.Event]struct{}{}
+	streamDone = make(chan struct{})
+	closeOnce  = sync.Once{}
+)
+
+// publishToStreams is the event subscriber feeding the group event streams.
+// Slow consumers drop events rather than block the bus.
+func publishToStreams(_ context.Context, e event.Event) {
+	streamLock.Lock()
+	defer streamLock.Unlock()
+
+	for stream := range streams[e.GroupID()] {
+		select {
+		case stream <- e:
+		default:
+			zerologr.Info("event stream is full, dropping event", "kind", e.Kind())
+		}
+	}
+}
+
+// CloseStreams ends all open group event streams, so that they do not hold up
+// a graceful server shutdown.
+func CloseStreams() {
+	closeOnce.Do(func() { close(streamDone) })
+}
+
+func openStream(groupID int) chan event.Event {
+	streamLock.Lock()
+	defer streamLock.Unlock()
+
+	stream := make(chan event.Event, streamBuffer)
+	if streams[groupID] == nil {
+		streams[groupID] = map[chan event.Event]struct{}{}
+	}
+	streams[groupID][stream] = struct{}{}
+	return stream
+}
+
+func closeStream(groupID int, stream chan event.Event) {
+	streamLock.Lock()
+	defer streamLock.Unlock()
+
+	delete(streams[groupID], stream)
+	if len(streams[groupID]) == 0 {
+		delete(streams, groupID)
+	}
+}
+
+// removes returns true if the event takes the email out of the group, ending
+// its stream.
+func removes(e event.Event, email string) bool {
+	switch e := e.(type) {
+	case *event.MemberKicked:
+		return e.Account.Email == email
+	case *event.MemberLeft:
+		return e.Account.Email == email
+	case *event.GroupDeleted:
+		return true
+	}
+	return false
+}
+
+// handleGroupStream streams the events of the group to a member, until the
+// member is no longer in the group.
+func handleGroupStream(w http.ResponseWriter, r *http.Request) {
+	groupID := strings.Split(r.URL.Path, "/")[2]
+
+	i, err := strconv.Atoi(groupID)
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	group, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	email := getUserEmailFromToken(r)
+	if !group.IsMember(email) {
+		logger(r).Error(err, "user is not a member of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// The server write timeout would otherwise end the stream after a few
+	// seconds.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		logger(r).Error(err, "failed to clear write deadline for event stream")
+	}
+
+	stream := openStream(group.ID)
+	defer closeStream(group.ID, stream)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-streamDone:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case e := <-stream:
+			data, err := json.Marshal(e)
+			if err != nil {
+				logger(r).Error(err, "failed to serialize event")
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Kind(), data)
+
+			// The member still learns of its removal before the stream ends.
+			if removes(e, email) {
+				rc.Flush()
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			logger(r).Error(err, "failed to flush event stream")
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/event"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestGroupStreamEndsOnRemoval(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+
+	token := loginAs(t, "bob@domain.se")
+	group := saveGroup(t, "alice@domain.se", "bob@domain.se")
+
+	server := httptest.NewServer(http.HandlerFunc(handleGroupStream))
+	defer server.Close()
+
+	req, err := http.NewRequestWithContext(t.Context(), "GET", server.URL+"/groups/1/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", token)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The stream is registered once the headers are sent.
+	publishToStreams(t.Context(), &event.MemberKicked{
+		Group:   group,
+		Account: &model.Account{Email: "bob@domain.se"},
+		By:      &model.Account{Email: "alice@domain.se"},
+	})
+
+	body := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+	select {
+	case data := <-body:
+		if !strings.Contains(data, "event: "+event.KindMemberKicked) {
+			t.Errorf("got stream %q, want the kick before it ends", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stream of a kicked member did not end")
+	}
+}
@@ -0,0 +1,179 @@
+//nolint:errcheck,gosec
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/model"
+)
+
+var (
+	errNoTarget    = errors.New("no email or tag given")
+	errTagNotFound = errors.New("no account with that tag")
+)
+
+// targetEmail returns the email of the account a request targets, given by
+// either the email or the tag query parameter.
+func targetEmail(r *http.Request) (string, error) {
+	query := r.URL.Query()
+	if email := query.Get("email"); email != "" {
+		return email, nil
+	}
+
+	tag := strings.TrimSpace(query.Get("tag"))
+	if tag == "" {
+		return "", errNoTarget
+	}
+
+	account, err := lookupTag(r.Context(), tag)
+	if err != nil {
+		return "", err
+	}
+	return account.Email, nil
+}
+
+// lookupTag resolves a tag to its account through the tag index. A stale entry
+// is removed, a missing one means that no account has the tag.
+func lookupTag(ctx context.Context, tag string) (*model.Account, error) {
+	db.AquireTableLock[*model.AccountTag](ctx)
+	defer db.ReleaseTableLock[*model.AccountTag]()
+
+	entry, err := db.Read(ctx, &model.AccountTag{Tag: tag})
+	if err != nil {
+		return nil, errTagNotFound
+	}
+
+	account, err := db.Read(ctx, &model.Account{Email: entry.Email})
+	if err != nil || account.Tag != tag {
+		_ = db.Delete(ctx, entry)
+		return nil, errTagNotFound
+	}
+	return account, nil
+}
+
+// rebuildTagIndex adds the tags missing from the index and removes the entries
+// no account has, repairing an index that was written to partially.
+func rebuildTagIndex(ctx context.Context) error {
+	db.AquireTableLock[*model.AccountTag](ctx)
+	defer db.ReleaseTableLock[*model.AccountTag]()
+
+	accounts, err := db.ReadAll[*model.Account](ctx)
+	if err != nil {
+		return err
+	}
+	entries, err := db.ReadAll[*model.AccountTag](ctx)
+	if err != nil {
+		return err
+	}
+
+	tags := map[string]string{}
+	for _, account := range accounts {
+		if account.Tag != "" {
+			tags[account.Tag] = account.Email
+		}
+	}
+	for _, entry := range entries {
+		if tags[entry.Tag] == entry.Email {
+			delete(tags, entry.Tag)
+			continue
+		}
+		//nolint:gosec,govet
+		if err := db.Delete(ctx, entry); err != nil {
+			return err
+		}
+	}
+	for tag, email := range tags {
+		//nolint:gosec,govet
+		if err := db.Save(ctx, &model.AccountTag{Tag: tag, Email: email}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexTag moves the tag index entry of an account from its previous tag to its
+// current one, either of which may be empty, and updates the tag of the
+// account in the groups it is a member of.
+func indexTag(ctx context.Context, previousTag string, account *model.Account) error {
+	if previousTag == account.Tag {
+		return nil
+	}
+
+	//nolint:gosec,govet
+	if err := moveTagIndex(ctx, previousTag, account); err != nil {
+		return err
+	}
+	if account.Email == "" {
+		return nil
+	}
+	// The group lock is taken after the index lock is released, handlers
+	// holding the group lock look tags up.
+	return retagMember(ctx, account)
+}
+
+func moveTagIndex(ctx context.Context, previousTag string, account *model.Account) error {
+	db.AquireTableLock[*model.AccountTag](ctx)
+	defer db.ReleaseTableLock[*model.AccountTag]()
+
+	if previousTag != "" {
+		_ = db.Delete(ctx, &model.AccountTag{Tag: previousTag})
+	}
+	if account.Tag == "" {
+		return nil
+	}
+	return db.Save(ctx, &model.AccountTag{Tag: account.Tag, Email: account.Email})
+}
+
+// retagMember copies the tag of the account to its memberships.
+func retagMember(ctx context.Context, account *model.Account) error {
+	db.AquireTableLock[*model.Group](ctx)
+	defer db.ReleaseTableLock[*model.Group]()
+
+	groups, err := db.ReadAll[*model.Group](ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		member := group.Member(account.Email)
+		if member == nil || member.Tag == account.Tag {
+			continue
+		}
+		member.Tag = account.Tag
+		//nolint:gosec,govet
+		if err := db.Save(ctx, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleUserGet looks up the public profile of the account with the tag.
+func handleUserGet(w http.ResponseWriter, r *http.Request) {
+	tag := strings.TrimSpace(r.URL.Path[len("/users/"):])
+
+	account, err := lookupTag(r.Context(), tag)
+	if errors.Is(err, errTagNotFound) {
+		logger(r).Error(err, "no account found matching the tag")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger(r).Error(err, "failed to look up tag")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, &model.Profile{Tag: account.Tag}); err != nil {
+		logger(r).Error(err, "failed to serialize profile")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestLookupTag(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.AccountTag](t.Context())
+	env.Parse()
+
+	// Saved without going through the handlers, so the index lacks the tag.
+	if err := db.Save(t.Context(), &model.Account{Email: "email@domain.se", Tag: "tag"}); err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	// A stale entry pointing at an account that no longer has the tag.
+	if err := db.Save(t.Context(), &model.AccountTag{Tag: "old", Email: "email@domain.se"}); err != nil {
+		t.Fatalf("failed to create tag index entry: %v", err)
+	}
+
+	if _, err := lookupTag(t.Context(), "tag"); !errors.Is(err, errTagNotFound) {
+		t.Errorf("expected tags missing from the index not to resolve, got %v", err)
+	}
+	if _, err := lookupTag(t.Context(), "old"); err == nil {
+		t.Error("expected the stale tag not to resolve")
+	}
+	if db.Exists(t.Context(), &model.AccountTag{Tag: "old"}) {
+		t.Error("expected the stale index entry to be removed")
+	}
+
+	if err := db.Save(t.Context(), &model.AccountTag{Tag: "old", Email: "email@domain.se"}); err != nil {
+		t.Fatalf("failed to create tag index entry: %v", err)
+	}
+	if err := rebuildTagIndex(t.Context()); err != nil {
+		t.Fatalf("failed to rebuild the tag index: %v", err)
+	}
+	if db.Exists(t.Context(), &model.AccountTag{Tag: "old"}) {
+		t.Error("expected the rebuild to remove the stale index entry")
+	}
+
+	account, err := lookupTag(t.Context(), "tag")
+	if err != nil || account.Email != "email@domain.se" {
+		t.Fatalf("got account %v and error %v", account, err)
+	}
+
+	req := httptest.NewRequest("GET", "/users/tag", nil)
+	recorder := httptest.NewRecorder()
+	handleUserGet(recorder, req)
+
+	if recorder.Code != 200 || recorder.Body.String() != "{\n  \"tag\": \"tag\"\n}" {
+		t.Errorf("got status %d and body %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestIndexTagRetagsMembers(t *testing.T) {
+	defer db.Clear[*model.AccountTag](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	env.Parse()
+
+	group := &model.Group{
+		ID:      1,
+		Name:    "Group 1",
+		Owner:   "owner@domain.se",
+		Members: []*model.Member{{Email: "member@domain.se", Tag: "old"}},
+	}
+	if err := db.Save(t.Context(), group); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+
+	if err := indexTag(t.Context(), "old", &model.Account{Email: "member@domain.se", Tag: "new"}); err != nil {
+		t.Fatalf("failed to index tag: %v", err)
+	}
+
+	group, err := db.Read(t.Context(), &model.Group{ID: 1})
+	if err != nil {
+		t.Fatalf("failed to read group: %v", err)
+	}
+	if group.Members[0].Tag != "new" {
+		t.Errorf("got member tag %q, want %q", group.Members[0].Tag, "new")
+	}
+}
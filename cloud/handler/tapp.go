@@ -2,135 +2,396 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
-	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/trebent/tapp-backend/db"
-	"github.com/trebent/tapp-backend/firebase"
+	"github.com/trebent/tapp-backend/event"
 	"github.com/trebent/tapp-backend/model"
-	"github.com/trebent/zerologr"
 )
 
+// handleTapp tapps the group. The body optionally holds the type, emoji and
+// message of the tapp and the ID of the tapp it answers, an empty body is a
+// plain tapp. The Location header points to the thread of the new tapp.
 func handleTapp(w http.ResponseWriter, r *http.Request) {
 	groupID := strings.Split(r.URL.Path, "/")[2]
 
 	i, err := strconv.Atoi(groupID)
 	if err != nil {
-		zerologr.Error(err, "failed to convert path parameter to integer")
+		logger(r).Error(err, "failed to convert path parameter to integer")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	group, err := db.Read(&model.Group{ID: i})
+	newTapp, err := readTapp(r)
 	if err != nil {
-		zerologr.Error(err, "group to tapp not found")
+		logger(r).Error(err, "failed to deserialize tapp")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonSerErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := newTapp.Validate(); err != nil {
+		logger(r).Error(err, "tapp is invalid")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	group, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group to tapp not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	email := getUserEmailFromToken(r)
 
-	account, err := db.Read(&model.Account{Email: email})
+	account, err := db.Read(r.Context(), &model.Account{Email: email})
 	if err != nil {
-		zerologr.Error(err, "account not found")
+		logger(r).Error(err, "account not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	isMember := email == group.Owner ||
-		slices.ContainsFunc(group.Members, func(a *model.Account) bool { return a.Email == email })
-	if !isMember {
-		zerologr.Error(err, "user is not a member of the group")
+	if !group.IsMember(email) {
+		logger(r).Error(err, "user is not a member of the group")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
-	newTapp := &model.Tapp{
-		Time:    time.Now().Local().UnixMilli(),
-		GroupID: group.ID,
-		User:    &model.Account{Email: email, Tag: account.Tag},
+	newTapp.GroupID = group.ID
+	db.SimpleAcquire(r.Context(), newTapp)
+	defer db.SimpleRelease(newTapp)
+
+	tapps, err := db.SimpleRead(r.Context(), &model.Tapp{GroupID: group.ID})
+	if err != nil {
+		logger(r).Error(err, "failed to read all tapps from DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+	model.NumberTapps(tapps)
+
+	var original *model.Tapp
+	if newTapp.ReplyTo != 0 {
+		if newTapp.ReplyTo > len(tapps) {
+			logger(r).Error(err, "tapp to reply to not found", "reply_to", newTapp.ReplyTo)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		original = tapps[newTapp.ReplyTo-1]
 	}
-	go firebase.SendMulticast(&firebase.TappNotification{
-		Title: fmt.Sprintf("Group %s was tapped!", group.Name),
-		Body: fmt.Sprintf(
-			"%s tapped group %s, tapp them back!", newTapp.User.UserIdentifier(), group.Name,
-		),
-		Time:    newTapp.Time,
-		Group:   group,
-		Account: newTapp.User,
-		Type:    "tapp",
-	})
 
-	db.SimpleAcquire(newTapp)
-	defer db.SimpleRelease(newTapp)
+	now := time.Now()
+	if wait := limiter.allow(group, email, now); wait > 0 {
+		logger(r).Info("tapp rate limited", "group", group.ID, "wait", wait)
+		writeTooManyTapps(w, wait)
+		return
+	}
+
+	newTapp.ID = len(tapps) + 1
+	newTapp.Time = now.Local().UnixMilli()
+	newTapp.User = &model.Account{Email: email, Tag: account.Tag}
+	newTapp.Type = newTapp.TypeOrDefault()
 
-	w.WriteHeader(http.StatusNoContent)
 	//nolint:gosec,govet
-	if err := db.SimpleAppend(newTapp); err != nil {
-		zerologr.Error(err, "failed to save tapp to DB")
+	if err := db.SimpleAppend(r.Context(), newTapp); err != nil {
+		logger(r).Error(err, "failed to save tapp to DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
+	limiter.record(group, email, now)
+
+	event.Publish(r.Context(), &event.TappCreated{Group: group, Tapp: newTapp, Original: original})
+
+	w.Header().Set("Location", fmt.Sprintf("/groups/%d/tapp/%d", group.ID, newTapp.ID))
+	w.WriteHeader(http.StatusNoContent)
 }
 
+// readTapp reads the type, emoji, message and reply reference of a new tapp
+// from the request body, which may be empty.
+func readTapp(r *http.Request) (*model.Tapp, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	newTapp := &model.Tapp{}
+	if len(bytes.TrimSpace(data)) != 0 {
+		if newTapp, err = model.Deserialize(bytes.NewReader(data), newTapp); err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.Tapp{
+		Type:    newTapp.Type,
+		Emoji:   newTapp.Emoji,
+		Message: strings.TrimSpace(newTapp.Message),
+		ReplyTo: newTapp.ReplyTo,
+	}, nil
+}
+
+// handleTappGet lists a page of the tapp history of the group, newest first.
+// The body is a plain array of tapps, the cursor of the next page is returned
+// in the X-Next-Cursor header.
 func handleTappGet(w http.ResponseWriter, r *http.Request) {
 	groupID := strings.Split(r.URL.Path, "/")[2]
 
 	i, err := strconv.Atoi(groupID)
 	if err != nil {
-		zerologr.Error(err, "failed to convert path parameter to integer")
+		logger(r).Error(err, "failed to convert path parameter to integer")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write(jsonFormatErr)
 		return
 	}
 
-	group, err := db.Read(&model.Group{ID: i})
+	group, err := db.Read(r.Context(), &model.Group{ID: i})
 	if err != nil {
-		zerologr.Error(err, "group not found")
+		logger(r).Error(err, "group not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	email := getUserEmailFromToken(r)
 
-	isMember := email == group.Owner ||
-		slices.ContainsFunc(group.Members, func(a *model.Account) bool { return a.Email == email })
-	if !isMember {
-		zerologr.Error(err, "user is not a member of the group")
+	if !group.IsMember(email) {
+		logger(r).Error(err, "user is not a member of the group")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	query, err := parseTappQuery(r)
+	if err != nil {
+		logger(r).Error(err, "bad tapp history query")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	tapps, err := db.SimpleRead(r.Context(), &model.Tapp{GroupID: group.ID})
+	if err != nil {
+		logger(r).Error(err, "failed to read all tapps from DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	model.NumberTapps(tapps)
+	page, next, err := pageTapps(tapps, query)
+	if err != nil {
+		logger(r).Error(err, "bad tapp history cursor")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+
+	//nolint:gosec,govet
+	if err := attachAcks(r.Context(), group.ID, page); err != nil {
+		logger(r).Error(err, "failed to read all tapp acks from DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
+	}
+
+	//nolint:gosec,govet
+	if err := model.WriteJSON(w, page); err != nil {
+		logger(r).Error(err, "failed to serialize tapps")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonSerErr)
+		return
+	}
+}
+
+// handleTappThread shows a tapp along with the replies to it and how long each
+// of them took.
+func handleTappThread(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	i, err := strconv.Atoi(parts[2])
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	tappID, err := strconv.Atoi(parts[4])
+	if err != nil {
+		logger(r).Error(err, "failed to convert path parameter to integer")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(jsonFormatErr)
+		return
+	}
+
+	group, err := db.Read(r.Context(), &model.Group{ID: i})
+	if err != nil {
+		logger(r).Error(err, "group not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !group.IsMember(getUserEmailFromToken(r)) {
+		logger(r).Error(err, "user is not a member of the group")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
-	tapps, err := db.SimpleRead(&model.Tapp{GroupID: group.ID})
+	tapps, err := db.SimpleRead(r.Context(), &model.Tapp{GroupID: group.ID})
 	if err != nil {
-		zerologr.Error(err, "failed to read all tapps from DB")
+		logger(r).Error(err, "failed to read all tapps from DB")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonDBErr)
 		return
 	}
+	model.NumberTapps(tapps)
 
-	slices.SortFunc(tapps, func(a, b *model.Tapp) int {
-		return int(b.Time - a.Time)
-	})
+	thread := model.Thread(tapps, tappID)
+	if thread == nil {
+		logger(r).Error(err, "tapp not found", "tapp", tappID)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
 
-	//nolint:mnd
-	if len(tapps) > 50 {
-		//nolint:mnd
-		tapps = tapps[:50]
+	//nolint:gosec,govet
+	if err := attachAcks(r.Context(), group.ID, []*model.Tapp{thread.Tapp}); err != nil {
+		logger(r).Error(err, "failed to read all tapp acks from DB")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(jsonDBErr)
+		return
 	}
-	tapps = slices.DeleteFunc(tapps, func(t *model.Tapp) bool { return t.GroupID != group.ID })
 
 	//nolint:gosec,govet
-	if err := model.WriteJSON(w, tapps); err != nil {
-		zerologr.Error(err, "failed to serialize tapps")
+	if err := model.WriteJSON(w, thread); err != nil {
+		logger(r).Error(err, "failed to serialize tapp thread")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(jsonSerErr)
 		return
 	}
 }
+
+const (
+	defaultTappPageSize = 50
+	maxTappPageSize     = 200
+)
+
+// tappQuery holds the history filters of GET /groups/{group}/tapp. Since and
+// Until are inclusive UNIX millis, zero meaning unbounded.
+type tappQuery struct {
+	limit    int
+	cursor   int
+	since    int64
+	until    int64
+	user     string
+	tappType model.TappType
+}
+
+func parseTappQuery(r *http.Request) (*tappQuery, error) {
+	values := r.URL.Query()
+	query := &tappQuery{
+		limit:    defaultTappPageSize,
+		cursor:   -1,
+		user:     values.Get("user"),
+		tappType: model.TappType(values.Get("type")),
+	}
+
+	if query.tappType != "" && !query.tappType.Valid() {
+		return nil, fmt.Errorf("unknown tapp type: %q", query.tappType)
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxTappPageSize {
+			return nil, fmt.Errorf("limit must be between 1 and %d: %q", maxTappPageSize, v)
+		}
+		query.limit = limit
+	}
+
+	if v := values.Get("cursor"); v != "" {
+		cursor, err := decodeTappCursor(v)
+		if err != nil {
+			return nil, err
+		}
+		query.cursor = cursor
+	}
+
+	for name, target := range map[string]*int64{"since": &query.since, "until": &query.until} {
+		if v := values.Get(name); v != "" {
+			t, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be UNIX millis: %w", name, err)
+			}
+			*target = t
+		}
+	}
+
+	return query, nil
+}
+
+// pageTapps walks the tapp log from the newest entry backwards, returning at
+// most query.limit tapps matching the filters and the cursor for the next
+// page, empty if there is none. The cursor is a position in the append-only
+// log, so pages stay stable while new tapps are added. A cursor outside of the
+// log is an error, restarting from the newest tapp would loop clients forever.
+func pageTapps(tapps []*model.Tapp, query *tappQuery) ([]*model.Tapp, string, error) {
+	start := len(tapps) - 1
+	if query.cursor >= 0 {
+		if query.cursor == 0 || query.cursor > len(tapps) {
+			return nil, "", fmt.Errorf("cursor out of range: %d", query.cursor)
+		}
+		start = query.cursor - 1
+	}
+
+	page := make([]*model.Tapp, 0, min(query.limit, len(tapps)))
+	for i := start; i >= 0; i-- {
+		t := tapps[i]
+		if query.since != 0 && t.Time < query.since {
+			// The log is in time order, nothing older can match.
+			break
+		}
+		if query.until != 0 && t.Time > query.until {
+			continue
+		}
+		if query.user != "" && t.User.Email != query.user && t.User.Tag != query.user {
+			continue
+		}
+		if query.tappType != "" && t.TypeOrDefault() != query.tappType {
+			continue
+		}
+
+		if len(page) == query.limit {
+			return page, encodeTappCursor(i + 1), nil
+		}
+		page = append(page, t)
+	}
+
+	return page, "", nil
+}
+
+func encodeTappCursor(position int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(position)))
+}
+
+func decodeTappCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("malformed cursor: %w", err)
+	}
+	position, err := strconv.Atoi(string(data))
+	if err != nil || position < 0 {
+		return 0, fmt.Errorf("malformed cursor: %q", cursor)
+	}
+	return position, nil
+}
@@ -27,3 +27,65 @@ func TestSortTappsByTime(t *testing.T) {
 		}
 	}
 }
+
+func TestPageTapps(t *testing.T) {
+	tapps := []*model.Tapp{}
+	for i := range 7 {
+		user := &model.Account{Email: "email@domain.se"}
+		if i%2 == 0 {
+			user = &model.Account{Email: "email2@domain.se", Tag: "tag2"}
+		}
+		tapps = append(tapps, &model.Tapp{Time: int64(1000 * (i + 1)), GroupID: 1, User: user})
+	}
+
+	page, next, _ := pageTapps(tapps, &tappQuery{limit: 3, cursor: -1})
+	if len(page) != 3 || page[0].Time != 7000 || page[2].Time != 5000 {
+		t.Fatalf("unexpected first page: %v", page)
+	}
+	if next == "" {
+		t.Fatal("expected a next cursor")
+	}
+
+	cursor, err := decodeTappCursor(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, next, _ = pageTapps(tapps, &tappQuery{limit: 3, cursor: cursor})
+	if len(page) != 3 || page[0].Time != 4000 || page[2].Time != 2000 {
+		t.Fatalf("unexpected second page: %v", page)
+	}
+
+	cursor, _ = decodeTappCursor(next)
+	page, next, _ = pageTapps(tapps, &tappQuery{limit: 3, cursor: cursor})
+	if len(page) != 1 || page[0].Time != 1000 || next != "" {
+		t.Fatalf("unexpected last page: %v, next %q", page, next)
+	}
+
+	page, _, _ = pageTapps(tapps, &tappQuery{limit: 50, cursor: -1, since: 2000, until: 5000})
+	if len(page) != 4 || page[0].Time != 5000 || page[3].Time != 2000 {
+		t.Fatalf("unexpected time filtered page: %v", page)
+	}
+
+	page, _, _ = pageTapps(tapps, &tappQuery{limit: 50, cursor: -1, user: "tag2"})
+	if len(page) != 4 {
+		t.Fatalf("got %d tapps for tag2, want %d", len(page), 4)
+	}
+
+	tapps[2].Type = model.TappTypeUrgent
+	page, _, _ = pageTapps(tapps, &tappQuery{limit: 50, cursor: -1, tappType: model.TappTypeUrgent})
+	if len(page) != 1 || page[0].Time != 3000 {
+		t.Fatalf("unexpected urgent page: %v", page)
+	}
+
+	page, _, _ = pageTapps(tapps, &tappQuery{limit: 50, cursor: -1, tappType: model.TappTypeTapp})
+	if len(page) != 6 {
+		t.Fatalf("got %d plain tapps, want %d", len(page), 6)
+	}
+
+	if _, _, err := pageTapps(tapps, &tappQuery{limit: 3, cursor: 8}); err == nil {
+		t.Error("expected an error for a cursor past the end of the log")
+	}
+	if _, _, err := pageTapps(tapps, &tappQuery{limit: 3, cursor: 0}); err == nil {
+		t.Error("expected an error for a cursor at the start of the log")
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/trebent/tapp-backend/db"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/model"
+)
+
+func TestTappReplies(t *testing.T) {
+	defer db.Clear[*model.Account](t.Context())
+	defer db.Clear[*model.Group](t.Context())
+	defer db.SimpleClear(t.Context(), &model.Tapp{GroupID: 1})
+	env.Parse()
+	limiter = newTappLimiter()
+
+	tokens := map[string]string{
+		"alice@domain.se": loginAs(t, "alice@domain.se"),
+		"bob@domain.se":   loginAs(t, "bob@domain.se"),
+	}
+	saveGroup(t, "alice@domain.se", "bob@domain.se")
+
+	tests := []tc{
+		{name: "Tapp", token: tokens["alice@domain.se"], wantStatus: 204},
+		{name: "Reply to unknown tapp", token: tokens["bob@domain.se"], body: `{"reply_to":2}`, wantStatus: 404},
+		{name: "Reply", token: tokens["bob@domain.se"], body: `{"reply_to":1}`, wantStatus: 204},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/groups/1/tapp", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.token)
+			recorder := httptest.NewRecorder()
+
+			handleTapp(recorder, req)
+
+			if recorder.Result().StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/groups/1/tapp/1", nil)
+	req.Header.Set("Authorization", tokens["bob@domain.se"])
+	recorder := httptest.NewRecorder()
+	handleTappThread(recorder, req)
+	if recorder.Code != 200 {
+		t.Fatalf("got status %d, want %d", recorder.Code, 200)
+	}
+
+	thread := &model.TappThread{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), thread); err != nil {
+		t.Fatalf("failed to unmarshal thread: %v", err)
+	}
+	if thread.Tapp.ID != 1 || len(thread.Replies) != 1 || thread.Replies[0].Tapp.ID != 2 ||
+		thread.Replies[0].Tapp.Type != model.TappTypeTappBack {
+		t.Errorf("unexpected thread: %+v", thread)
+	}
+}
@@ -14,8 +14,11 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/trebent/envparser"
 	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/tapp-backend/event"
 	"github.com/trebent/tapp-backend/firebase"
 	"github.com/trebent/tapp-backend/handler"
+	"github.com/trebent/tapp-backend/metrics"
+	"github.com/trebent/tapp-backend/tracing"
 	"github.com/trebent/zerologr"
 )
 
@@ -45,18 +48,36 @@ func main() {
 		V:       env.LogLevel.Value(),
 	}))
 
+	shutdownTracing, err := tracing.Initialize(context.Background())
+	if err != nil {
+		zerologr.Error(err, "failed to initialize tracing")
+		os.Exit(1)
+	}
+
 	handler.Initialize()
 	firebase.Initialize()
 
 	signalCtx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	firebase.Subscribe(signalCtx)
+	event.SubscribeAll(event.Audit)
+	event.SubscribeAll(event.Count)
+
 	httpServer := &http.Server{
 		Addr:         env.Addr.Value(),
 		Handler:      handler.Handler(),
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
 	}
+	httpServer.RegisterOnShutdown(handler.CloseStreams)
+
+	metricsServer := &http.Server{
+		Addr:         env.MetricsAddr.Value(),
+		Handler:      metrics.Handler(),
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+	}
 
 	go func() {
 		zerologr.Info("starting tapp cloud service on port " + env.Addr.Value())
@@ -68,12 +89,56 @@ func main() {
 		zerologr.Info("server stopped gracefully")
 	}()
 
+	go handler.SweepInvitations(signalCtx)
+	go handler.PurgeGroups(signalCtx)
+	go handler.RunSchedules(signalCtx)
+	go firebase.SendDigests(signalCtx)
+	go firebase.RunOutbox(signalCtx)
+
+	go func() {
+		zerologr.Info("starting metrics server on port " + env.MetricsAddr.Value())
+		if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) &&
+			err != nil {
+			zerologr.Error(err, "metrics server start failed")
+			//nolint:gocritic // I know.
+			os.Exit(1)
+		}
+	}()
+
 	<-signalCtx.Done()
 	zerologr.Info("server shutting down")
+
+	// Fail readiness first and give load balancers time to stop routing here.
+	handler.SetShuttingDown()
+	time.Sleep(time.Duration(env.ShutdownDrainSeconds.Value()) * time.Second)
+
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer shutdownCancel()
-	if err := httpServer.Shutdown(shutdownCtx); err != nil {
-		zerologr.Error(err, "server shutdown failed")
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		zerologr.Error(err, "metrics server shutdown failed")
+	}
+	// A failed shutdown still lets pending pushes drain before exiting.
+	shutdownErr := httpServer.Shutdown(shutdownCtx)
+	if shutdownErr != nil {
+		zerologr.Error(shutdownErr, "server shutdown failed")
+	}
+
+	// Let in-flight subscribers, such as push notifications, finish.
+	event.Wait()
+
+	// The outbox gets its own time, the shutdown above may have used up most
+	// of shutdownCtx.
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer drainCancel()
+	firebase.DrainOutbox(drainCtx)
+
+	tracingCtx, tracingCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer tracingCancel()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		zerologr.Error(err, "failed to flush traces")
+	}
+
+	if shutdownErr != nil {
 		//nolint:gocritic // I know.
 		os.Exit(1)
 	}
@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const namespace = "tapp"
+
+//nolint:gochecknoglobals
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests handled, by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	dbDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_operation_duration_seconds",
+		Help:      "Duration of DB operations, by operation and table.",
+		//nolint:mnd
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+	}, []string{"operation", "table"})
+	dbEntities = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "db_table_entities",
+		Help:      "Number of entities stored in a table, as of the last read or write.",
+	}, []string{"table"})
+
+	activeSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sessions",
+		Help:      "Number of logged in sessions.",
+	})
+
+	fcmMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fcm_messages_total",
+		Help:      "Number of FCM messages sent, by kind and result.",
+	}, []string{"kind", "result"})
+
+	fcmDeadLetters = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fcm_dead_letters_total",
+		Help:      "Number of FCM messages given up on after all delivery attempts, by kind.",
+	}, []string{"kind"})
+
+	events = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_total",
+		Help:      "Number of domain events published, by kind.",
+	}, []string{"kind"})
+)
+
+// Handler serves the Prometheus metrics of the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTP records a handled request. Route is the matched mux pattern, to
+// keep path parameters out of the label values.
+func ObserveHTTP(route, method string, code int, duration time.Duration) {
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	httpDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// DBTimer starts timing a DB operation, call the returned function once the
+// operation is done.
+func DBTimer(operation, table string) func() {
+	start := time.Now()
+	return func() {
+		dbDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	}
+}
+
+func SetTableEntities(table string, count int) {
+	dbEntities.WithLabelValues(table).Set(float64(count))
+}
+
+func SetActiveSessions(count int) {
+	activeSessions.Set(float64(count))
+}
+
+// FCMSent records the outcome of an FCM send, kind being individual or
+// multicast.
+func FCMSent(kind string, success, failure int) {
+	fcmMessages.WithLabelValues(kind, "success").Add(float64(success))
+	fcmMessages.WithLabelValues(kind, "failure").Add(float64(failure))
+}
+
+// FCMDeadLettered records a message of the kind that will not be retried.
+func FCMDeadLettered(kind string) {
+	fcmDeadLetters.WithLabelValues(kind).Inc()
+}
+
+func EventPublished(kind string) {
+	events.WithLabelValues(kind).Inc()
+}
+
+// EventCounts returns the number of domain events published so far, by kind.
+func EventCounts() map[string]int64 {
+	collected := make(chan prometheus.Metric)
+	go func() {
+		defer close(collected)
+		events.Collect(collected)
+	}()
+
+	counts := map[string]int64{}
+	for metric := range collected {
+		m := &dto.Metric{}
+		if err := metric.Write(m); err != nil {
+			continue
+		}
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "kind" {
+				counts[label.GetValue()] = int64(m.GetCounter().GetValue())
+			}
+		}
+	}
+	return counts
+}
@@ -0,0 +1,84 @@
+package model
+
+type AckState string
+
+const (
+	AckDelivered AckState = "delivered"
+	AckSeen      AckState = "seen"
+)
+
+// Valid returns true for the known acknowledgement states.
+func (s AckState) Valid() bool {
+	return s == AckDelivered || s == AckSeen
+}
+
+// SummarizeAcks sums up the acknowledgements of a group log per tapp ID, only
+// the furthest ack of each member counts.
+func SummarizeAcks(acks []*TappAck) map[int]*TappAcks {
+	furthest := map[int]map[string]*TappAck{}
+	order := map[int][]string{}
+	for _, ack := range acks {
+		members, ok := furthest[ack.TappID]
+		if !ok {
+			members = map[string]*TappAck{}
+			furthest[ack.TappID] = members
+		}
+
+		previous, ok := members[ack.Email]
+		if !ok {
+			order[ack.TappID] = append(order[ack.TappID], ack.Email)
+		}
+		if !ok || (previous.State == AckDelivered && ack.State == AckSeen) {
+			members[ack.Email] = ack
+		}
+	}
+
+	summaries := map[int]*TappAcks{}
+	for tappID, emails := range order {
+		summary := &TappAcks{Members: make([]*TappAck, 0, len(emails))}
+		for _, email := range emails {
+			ack := furthest[tappID][email]
+			summary.Members = append(summary.Members, ack)
+			summary.Delivered++
+			if ack.State == AckSeen {
+				summary.Seen++
+			}
+		}
+		summaries[tappID] = summary
+	}
+	return summaries
+}
+
+// StateOf returns the furthest state the email has acknowledged, or an empty
+// state if it has not acknowledged the tapp.
+func (a *TappAcks) StateOf(email string) AckState {
+	if a == nil {
+		return ""
+	}
+	for _, ack := range a.Members {
+		if ack.Email == email {
+			return ack.State
+		}
+	}
+	return ""
+}
+
+// SeenByAll returns true if every member of the group but the sender has
+// seen the tapp.
+func (a *TappAcks) SeenByAll(group *Group, sender string) bool {
+	recipients := append([]string{group.Owner}, emailsOf(group.Members)...)
+	for _, email := range recipients {
+		if email != sender && a.StateOf(email) != AckSeen {
+			return false
+		}
+	}
+	return true
+}
+
+func emailsOf(members []*Member) []string {
+	emails := make([]string, 0, len(members))
+	for _, member := range members {
+		emails = append(emails, member.Email)
+	}
+	return emails
+}
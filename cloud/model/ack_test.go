@@ -0,0 +1,32 @@
+package model
+
+import "testing"
+
+func TestSummarizeAcks(t *testing.T) {
+	acks := []*TappAck{
+		{TappID: 1, Email: "bob", State: AckDelivered},
+		{TappID: 1, Email: "carol", State: AckSeen},
+		{TappID: 1, Email: "bob", State: AckSeen},
+		{TappID: 1, Email: "carol", State: AckDelivered},
+		{TappID: 2, Email: "bob", State: AckDelivered},
+	}
+
+	summaries := SummarizeAcks(acks)
+	if got := summaries[1]; got.Delivered != 2 || got.Seen != 2 || len(got.Members) != 2 {
+		t.Errorf("unexpected summary of tapp 1: %+v", got)
+	}
+	if got := summaries[2]; got.Delivered != 1 || got.Seen != 0 {
+		t.Errorf("unexpected summary of tapp 2: %+v", got)
+	}
+	if summaries[3].StateOf("bob") != "" {
+		t.Error("expected no state for an unacknowledged tapp")
+	}
+
+	group := &Group{Owner: "alice", Members: []*Member{{Email: "bob"}, {Email: "carol"}}}
+	if !summaries[1].SeenByAll(group, "alice") || summaries[2].SeenByAll(group, "alice") {
+		t.Error("unexpected seen by all")
+	}
+	if summaries[1].SeenByAll(group, "bob") {
+		t.Error("the owner has not seen the tapp of bob")
+	}
+}
@@ -0,0 +1,23 @@
+package model
+
+import "errors"
+
+var (
+	ErrCodeRevoked = errors.New("invite code has been revoked")
+	ErrCodeExpired = errors.New("invite code has expired")
+	ErrCodeUsedUp  = errors.New("invite code has no uses left")
+)
+
+// Usable returns an error describing why the code can no longer be used to
+// join its group at the time now, in UNIX millis.
+func (c *InviteCode) Usable(now int64) error {
+	switch {
+	case c.Revoked != 0:
+		return ErrCodeRevoked
+	case c.Expires != 0 && now >= c.Expires:
+		return ErrCodeExpired
+	case c.MaxUses != 0 && c.Uses >= c.MaxUses:
+		return ErrCodeUsedUp
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInviteCodeUsable(t *testing.T) {
+	tests := []struct {
+		name string
+		code *InviteCode
+		want error
+	}{
+		{name: "unlimited", code: &InviteCode{Uses: 100}},
+		{name: "not expired", code: &InviteCode{Expires: 11}},
+		{name: "expired", code: &InviteCode{Expires: 10}, want: ErrCodeExpired},
+		{name: "uses left", code: &InviteCode{MaxUses: 2, Uses: 1}},
+		{name: "used up", code: &InviteCode{MaxUses: 2, Uses: 2}, want: ErrCodeUsedUp},
+		{name: "revoked", code: &InviteCode{Revoked: 5, Expires: 10}, want: ErrCodeRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.code.Usable(10); !errors.Is(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrCron = errors.New("invalid cron expression")
+
+// cronSearchLimit bounds the search for the next run, expressions like the
+// 31st of February never match.
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// Cron is a parsed five field cron expression: minute, hour, day of month,
+// month and day of week, with Sunday as 0 or 7. Fields take *, single values,
+// ranges like 1-5, steps like */15 or 0-30/10, and comma separated lists of
+// those.
+type Cron struct {
+	minutes  []bool
+	hours    []bool
+	days     []bool
+	months   []bool
+	weekdays []bool
+	// Like in cron, a day matches either field if both the day of month and
+	// the day of week are restricted.
+	anyDay     bool
+	anyWeekday bool
+}
+
+func ParseCron(spec string) (*Cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: want 5 fields, got %d", ErrCron, len(fields))
+	}
+
+	c := &Cron{anyDay: fields[2] == "*", anyWeekday: fields[4] == "*"}
+	targets := []struct {
+		field    *[]bool
+		min, max int
+	}{
+		{&c.minutes, 0, 59},
+		{&c.hours, 0, 23},
+		{&c.days, 1, 31},
+		{&c.months, 1, 12},
+		{&c.weekdays, 0, 7},
+	}
+	for i, target := range targets {
+		set, err := parseCronField(fields[i], target.min, target.max)
+		if err != nil {
+			return nil, err
+		}
+		*target.field = set
+	}
+	c.weekdays[0] = c.weekdays[0] || c.weekdays[7]
+
+	return c, nil
+}
+
+func parseCronField(field string, low, high int) ([]bool, error) {
+	set := make([]bool, high+1)
+	for part := range strings.SplitSeq(field, ",") {
+		rng, step := part, 1
+		if before, after, ok := strings.Cut(part, "/"); ok {
+			var err error
+			if step, err = strconv.Atoi(after); err != nil || step < 1 {
+				return nil, fmt.Errorf("%w: bad step in %q", ErrCron, part)
+			}
+			rng = before
+		}
+
+		start, end := low, high
+		if rng != "*" {
+			first, last, isRange := strings.Cut(rng, "-")
+			var err error
+			if start, err = strconv.Atoi(first); err != nil {
+				return nil, fmt.Errorf("%w: bad value in %q", ErrCron, part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(last); err != nil {
+					return nil, fmt.Errorf("%w: bad range in %q", ErrCron, part)
+				}
+			}
+		}
+		if start < low || end > high || start > end {
+			return nil, fmt.Errorf("%w: %q is out of range %d-%d", ErrCron, part, low, high)
+		}
+
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	day, weekday := c.days[t.Day()], c.weekdays[t.Weekday()]
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Next returns the first minute after the time that matches the expression,
+// in the location of the time, or the zero time if there is none.
+func (c *Cron) Next(after time.Time) time.Time {
+	loc := after.Location()
+	// The search runs on wall clock times, so that a time skipped when the
+	// clocks go forward still runs, just later, and a time repeated when they
+	// go back runs once.
+	t := time.Date(
+		after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), 0, 0, time.UTC,
+	).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		switch {
+		case !c.months[t.Month()]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !c.hours[t.Hour()]:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !c.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+			if local.After(after) {
+				return local
+			}
+			t = t.Add(time.Minute)
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Skipf("no timezone data: %v", err)
+	}
+	// A Friday.
+	friday := time.Date(2025, 3, 14, 9, 30, 0, 0, stockholm)
+
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"0 8 * * 1-5", friday, time.Date(2025, 3, 17, 8, 0, 0, 0, stockholm)},
+		{"30 19 * * *", friday, time.Date(2025, 3, 14, 19, 30, 0, 0, stockholm)},
+		{"*/15 * * * *", friday, time.Date(2025, 3, 14, 9, 45, 0, 0, stockholm)},
+		{"0 12 1 * 0", friday, time.Date(2025, 3, 16, 12, 0, 0, 0, stockholm)},
+		{"0 0 29 2 *", friday, time.Date(2028, 2, 29, 0, 0, 0, 0, stockholm)},
+		// The clocks go forward at 02:00 on the last Sunday of March.
+		{"30 2 30 3 *", friday, time.Date(2025, 3, 30, 3, 30, 0, 0, stockholm)},
+		{"0 0 31 2 *", friday, time.Time{}},
+	}
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.spec, err)
+		}
+		if got := cron.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := ParseCron(spec); !errors.Is(err, ErrCron) {
+			t.Errorf("%s: got error %v, want %v", spec, err, ErrCron)
+		}
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	now := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		want     error
+	}{
+		{name: "Once", schedule: &Schedule{At: now.Add(time.Hour).UnixMilli()}},
+		{name: "Weekdays", schedule: &Schedule{Cron: "0 8 * * 1-5", Timezone: "UTC"}},
+		{name: "Past", schedule: &Schedule{At: now.Add(-time.Hour).UnixMilli()}, want: ErrScheduleTime},
+		{name: "Neither", schedule: &Schedule{}, want: ErrScheduleTime},
+		{name: "Both", schedule: &Schedule{At: now.Add(time.Hour).UnixMilli(), Cron: "* * * * *"}, want: ErrScheduleTime},
+		{name: "Bad cron", schedule: &Schedule{Cron: "every day"}, want: ErrCron},
+		{name: "Bad timezone", schedule: &Schedule{Cron: "* * * * *", Timezone: "Mars/Olympus"}, want: ErrScheduleTimezone},
+		{name: "Bad tapp", schedule: &Schedule{Cron: "* * * * *", Type: "poke"}, want: ErrTappType},
+	}
+	for _, tt := range tests {
+		if err := tt.schedule.Validate(now.UnixMilli()); !errors.Is(err, tt.want) || (err == nil) != (tt.want == nil) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	once := &Schedule{At: now.Add(time.Hour).UnixMilli()}
+	if once.NextRun(now) != once.At || once.NextRun(now.Add(2*time.Hour)) != 0 {
+		t.Error("one-off schedules should run once")
+	}
+}
@@ -13,24 +13,204 @@ type (
 		Email    string `json:"email"`
 		Password string `json:"password,omitempty"`
 	}
+	// Preferences are the notification preferences of an account. Quiet hours
+	// run from QuietStart to QuietEnd, wall clock times like 22:00 in the
+	// Timezone, and may span midnight.
+	Preferences struct {
+		Email      string    `json:"email"`
+		Timezone   string    `json:"timezone,omitempty"`
+		QuietStart string    `json:"quiet_start,omitempty"`
+		QuietEnd   string    `json:"quiet_end,omitempty"`
+		QuietMode  QuietMode `json:"quiet_mode,omitempty"`
+	}
+	// Digest collects the pushes deferred during the quiet hours of an
+	// account, delivered as one push once they end.
+	Digest struct {
+		Email string        `json:"email"`
+		Items []*DigestItem `json:"items"`
+	}
+	DigestItem struct {
+		GroupID   int    `json:"group_id"`
+		GroupName string `json:"group_name"`
+		Count     int    `json:"count"`
+		Last      int64  `json:"last"`
+	}
+	// AccountTag indexes accounts by their unique tag.
+	AccountTag struct {
+		Tag   string `json:"tag"`
+		Email string `json:"email"`
+	}
+	// Profile is the public part of an account, shown to other users.
+	Profile struct {
+		Tag string `json:"tag"`
+	}
 	Group struct {
 		ID      int        `json:"id,omitempty"`
 		Name    string     `json:"name"`
 		Emoji   string     `json:"emoji"`
 		Desc    string     `json:"description,omitempty"`
 		Owner   string     `json:"owner,omitempty"`
-		Members []*Account `json:"members,omitempty"`
+		Members []*Member  `json:"members,omitempty"`
 		Invites []*Account `json:"invites,omitempty"`
+		// PendingOwner is the member that has been offered the ownership, but
+		// has not accepted it yet.
+		PendingOwner string `json:"pending_owner,omitempty"`
+		// Archived is the time, in UNIX millis, the group was archived. Archived
+		// groups are read-only and purged once the restore window has passed.
+		Archived int64 `json:"archived,omitempty"`
+		// Avatar is the path the group image is served from, versioned so that
+		// clients can cache it.
+		Avatar string `json:"avatar,omitempty"`
+		// Bans lists the accounts that may not be invited to or join the group.
+		Bans []*Ban `json:"bans,omitempty"`
+		// TappCooldown is the number of seconds a member has to wait between
+		// tapps, and TappRateLimit the number of tapps the group accepts per
+		// minute. The server defaults apply when zero.
+		TappCooldown  int `json:"tapp_cooldown,omitempty"`
+		TappRateLimit int `json:"tapp_rate_limit,omitempty"`
+		// OwnerSettings are the member settings of the owner, who has no
+		// membership entry.
+		OwnerSettings *MemberSettings `json:"owner_settings,omitempty"`
+	}
+	Ban struct {
+		Email string `json:"email"`
+		Tag   string `json:"tag,omitempty"`
+		By    string `json:"by"`
+		Time  int64  `json:"time"`
+	}
+	// Blocklist holds the accounts a user has blocked. Blocked accounts can not
+	// invite the user, and their tapps are not pushed to the user.
+	Blocklist struct {
+		Email   string     `json:"email"`
+		Blocked []*Account `json:"blocked"`
+	}
+	// Member is a group membership. The owner is not listed as a member, see
+	// Group.Owner.
+	Member struct {
+		Email  string `json:"email"`
+		Tag    string `json:"tag,omitempty"`
+		Role   Role   `json:"role,omitempty"`
+		Joined int64  `json:"joined,omitempty"`
+		// InviteCode is the code the member joined with, if any.
+		InviteCode string `json:"invite_code,omitempty"`
+		MemberSettings
+	}
+	// MemberSettings are the personal settings of a member in a group.
+	MemberSettings struct {
+		Nickname string `json:"nickname,omitempty"`
+		// MutedUntil is the time, in UNIX millis, until which no pushes are
+		// sent to the member.
+		MutedUntil int64       `json:"muted_until,omitempty"`
+		Notify     NotifyLevel `json:"notify,omitempty"`
+	}
+	// Quota overrides the default limits for an account, zero values keep the
+	// default.
+	Quota struct {
+		Email             string `json:"email"`
+		MaxGroupMembers   int    `json:"max_group_members,omitempty"`
+		MaxOwnedGroups    int    `json:"max_owned_groups,omitempty"`
+		MaxPendingInvites int    `json:"max_pending_invites,omitempty"`
 	}
 	Invitation struct {
 		GroupID   int    `json:"group_id"`
 		GroupName string `json:"group_name"`
 		Email     string `json:"email"`
+		InvitedBy string `json:"invited_by,omitempty"`
+		Created   int64  `json:"created,omitempty"`
+		Expires   int64  `json:"expires,omitempty"`
+	}
+	// InviteCode is a shareable code that lets anyone holding it join a group
+	// without an invitation to their email. Expires and MaxUses are unlimited
+	// when zero.
+	InviteCode struct {
+		Code      string `json:"code"`
+		GroupID   int    `json:"group_id"`
+		CreatedBy string `json:"created_by"`
+		Created   int64  `json:"created"`
+		Expires   int64  `json:"expires,omitempty"`
+		MaxUses   int    `json:"max_uses,omitempty"`
+		Uses      int    `json:"uses"`
+		Revoked   int64  `json:"revoked,omitempty"`
+		Link      string `json:"link,omitempty"`
 	}
 	Tapp struct {
+		// ID is the position of the tapp in the log of its group, starting at
+		// one. It is stable since the log is append-only.
+		ID      int      `json:"id,omitempty"`
 		Time    int64    `json:"time"`
 		GroupID int      `json:"group_id"`
 		User    *Account `json:"user"`
+		Type    TappType `json:"type,omitempty"`
+		// Emoji is set for emoji tapps only.
+		Emoji   string `json:"emoji,omitempty"`
+		Message string `json:"message,omitempty"`
+		// ReplyTo is the ID of the tapp this tapp answers, if any.
+		ReplyTo int `json:"reply_to,omitempty"`
+		// Acks is filled in when tapps are listed, it is not stored with the
+		// tapp.
+		Acks *TappAcks `json:"acks,omitempty"`
+	}
+	// TappAck records that a member has received or seen a tapp.
+	TappAck struct {
+		GroupID int      `json:"group_id"`
+		TappID  int      `json:"tapp_id"`
+		Email   string   `json:"email"`
+		Tag     string   `json:"tag,omitempty"`
+		State   AckState `json:"state"`
+		Time    int64    `json:"time"`
+	}
+	// TappAcks sums up the acknowledgements of a tapp. Seen tapps count as
+	// delivered too, and Members holds the furthest ack of each member.
+	TappAcks struct {
+		Delivered int        `json:"delivered"`
+		Seen      int        `json:"seen"`
+		Members   []*TappAck `json:"members"`
+	}
+	// OutboxEntry is a push waiting to be delivered to the Tokens. Failed
+	// deliveries are retried at NextAttempt until the attempts are used up.
+	OutboxEntry struct {
+		ID          int               `json:"id"`
+		Kind        string            `json:"kind"`
+		Tokens      []string          `json:"tokens"`
+		Data        map[string]string `json:"data"`
+		Created     int64             `json:"created"`
+		Attempts    int               `json:"attempts"`
+		NextAttempt int64             `json:"next_attempt"`
+		LastError   string            `json:"last_error,omitempty"`
+	}
+	// DeadLetter is an outbox entry given up on, kept apart from the outbox
+	// for a while to be looked into.
+	DeadLetter struct {
+		OutboxEntry
+		Died int64 `json:"died"`
+	}
+	// Schedule is a tapp to be made for a member later, once at the time At,
+	// or repeatedly following the Cron expression in the Timezone.
+	Schedule struct {
+		ID      int      `json:"id"`
+		GroupID int      `json:"group_id"`
+		Email   string   `json:"email"`
+		Type    TappType `json:"type,omitempty"`
+		Emoji   string   `json:"emoji,omitempty"`
+		Message string   `json:"message,omitempty"`
+		// At is the time, in UNIX millis, of a one-off tapp.
+		At       int64  `json:"at,omitempty"`
+		Cron     string `json:"cron,omitempty"`
+		Timezone string `json:"timezone,omitempty"`
+		// Next is the time, in UNIX millis, the schedule runs next.
+		Next    int64 `json:"next"`
+		Created int64 `json:"created"`
+		LastRun int64 `json:"last_run,omitempty"`
+	}
+	// TappThread is a tapp and the tapps answering it.
+	TappThread struct {
+		Tapp    *Tapp        `json:"tapp"`
+		Replies []*TappReply `json:"replies"`
+	}
+	TappReply struct {
+		Tapp *Tapp `json:"tapp"`
+		// ResponseTime is the number of millis between the tapp and the reply.
+		ResponseTime int64 `json:"response_time"`
 	}
 )
 
@@ -38,18 +218,60 @@ func (a *Account) Key() string {
 	return a.Email
 }
 
+func (t *AccountTag) Key() string {
+	return t.Tag
+}
+
+func (p *Preferences) Key() string {
+	return p.Email
+}
+
+func (d *Digest) Key() string {
+	return d.Email
+}
+
 func (g *Group) Key() string {
 	return strconv.Itoa(g.ID)
 }
 
+func (b *Blocklist) Key() string {
+	return b.Email
+}
+
+func (q *Quota) Key() string {
+	return q.Email
+}
+
 func (i *Invitation) Key() string {
 	return fmt.Sprintf("%d-%s", i.GroupID, i.Email)
 }
 
+func (c *InviteCode) Key() string {
+	return c.Code
+}
+
+func (s *Schedule) Key() string {
+	return strconv.Itoa(s.ID)
+}
+
+func (e *OutboxEntry) Key() string {
+	return strconv.Itoa(e.ID)
+}
+
+// Expired returns true if the invitation can no longer be accepted at the time
+// now, in UNIX millis.
+func (i *Invitation) Expired(now int64) bool {
+	return i.Expires != 0 && now >= i.Expires
+}
+
 func (t *Tapp) TableKey() string {
 	return strconv.Itoa(t.GroupID)
 }
 
+func (a *TappAck) TableKey() string {
+	return strconv.Itoa(a.GroupID)
+}
+
 func (a *Account) UserIdentifier() string {
 	if a.Tag != "" {
 		return a.Tag
@@ -8,14 +8,14 @@ import (
 )
 
 func TestAccount(t *testing.T) {
-	defer db.Clear[*Account]()
+	defer db.Clear[*Account](t.Context())
 	env.Parse()
 
-	if err := db.Save(&Account{Tag: "tag", Email: "email", Password: "password"}); err != nil {
+	if err := db.Save(t.Context(), &Account{Tag: "tag", Email: "email", Password: "password"}); err != nil {
 		t.Fatal(err)
 	}
 
-	entity, err := db.Read(&Account{Email: "email"})
+	entity, err := db.Read(t.Context(), &Account{Email: "email"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -24,11 +24,11 @@ func TestAccount(t *testing.T) {
 		t.Fatalf("expected email to be 'email', got '%s'", entity.Email)
 	}
 
-	if err := db.Save(&Account{Tag: "taggggggg", Email: "email", Password: "password2"}); err != nil {
+	if err := db.Save(t.Context(), &Account{Tag: "taggggggg", Email: "email", Password: "password2"}); err != nil {
 		t.Fatal(err)
 	}
 
-	accounts, err := db.ReadAll[*Account]()
+	accounts, err := db.ReadAll[*Account](t.Context())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -36,11 +36,11 @@ func TestAccount(t *testing.T) {
 		t.Fatalf("expected 1 account, got %d", len(accounts))
 	}
 
-	if err := db.Save(&Account{Tag: "taggggggg", Email: "email2", Password: "password2"}); err != nil {
+	if err := db.Save(t.Context(), &Account{Tag: "taggggggg", Email: "email2", Password: "password2"}); err != nil {
 		t.Fatal(err)
 	}
 
-	accounts, err = db.ReadAll[*Account]()
+	accounts, err = db.ReadAll[*Account](t.Context())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,7 +48,7 @@ func TestAccount(t *testing.T) {
 		t.Fatalf("expected 1 account, got %d", len(accounts))
 	}
 
-	entity, err = db.Read(&Account{Email: "email2"})
+	entity, err = db.Read(t.Context(), &Account{Email: "email2"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -57,11 +57,11 @@ func TestAccount(t *testing.T) {
 		t.Fatalf("expected email to be 'email2', got '%s'", entity.Email)
 	}
 
-	if err := db.Delete(&Account{Email: "email2"}); err != nil {
+	if err := db.Delete(t.Context(), &Account{Email: "email2"}); err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = db.Read(&Account{Email: "email2"})
+	_, err = db.Read(t.Context(), &Account{Email: "email2"})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
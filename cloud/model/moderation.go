@@ -0,0 +1,53 @@
+package model
+
+import "slices"
+
+// IsBanned returns true if the email is banned from the group.
+func (g *Group) IsBanned(email string) bool {
+	return slices.ContainsFunc(g.Bans, func(b *Ban) bool { return b.Email == email })
+}
+
+// Ban removes the account from the group, including any pending invite or
+// ownership offer, and bans it from coming back.
+func (g *Group) Ban(account *Account, by string, now int64) {
+	g.Members = slices.DeleteFunc(
+		g.Members, func(m *Member) bool { return m.Email == account.Email },
+	)
+	g.Invites = slices.DeleteFunc(
+		g.Invites, func(a *Account) bool { return a.Email == account.Email },
+	)
+	if g.PendingOwner == account.Email {
+		g.PendingOwner = ""
+	}
+
+	if !g.IsBanned(account.Email) {
+		g.Bans = append(g.Bans, &Ban{Email: account.Email, Tag: account.Tag, By: by, Time: now})
+	}
+}
+
+// Unban lifts the ban of the email, returning false if it was not banned.
+func (g *Group) Unban(email string) bool {
+	banned := g.IsBanned(email)
+	g.Bans = slices.DeleteFunc(g.Bans, func(b *Ban) bool { return b.Email == email })
+	return banned
+}
+
+// Blocks returns true if the email has been blocked.
+func (b *Blocklist) Blocks(email string) bool {
+	return slices.ContainsFunc(b.Blocked, func(a *Account) bool { return a.Email == email })
+}
+
+// Block adds the account to the blocklist, unless already blocked.
+func (b *Blocklist) Block(account *Account) {
+	if !b.Blocks(account.Email) {
+		b.Blocked = append(b.Blocked, &Account{Email: account.Email, Tag: account.Tag})
+	}
+}
+
+// Unblock removes the email from the blocklist, returning false if it was not
+// blocked.
+func (b *Blocklist) Unblock(email string) bool {
+	blocked := b.Blocks(email)
+	b.Blocked = slices.DeleteFunc(b.Blocked, func(a *Account) bool { return a.Email == email })
+	return blocked
+}
@@ -0,0 +1,44 @@
+package model
+
+import "testing"
+
+func TestGroupBan(t *testing.T) {
+	group := &Group{
+		Owner:        "owner",
+		PendingOwner: "member",
+		Members:      []*Member{{Email: "member"}, {Email: "other"}},
+		Invites:      []*Account{{Email: "invited"}},
+	}
+
+	group.Ban(&Account{Email: "member", Tag: "tag"}, "owner", 1)
+	group.Ban(&Account{Email: "member", Tag: "tag"}, "owner", 2)
+	group.Ban(&Account{Email: "invited"}, "owner", 3)
+
+	if group.IsMember("member") || group.PendingOwner != "" || len(group.Invites) != 0 {
+		t.Errorf("expected banned accounts to be removed, got %+v", group)
+	}
+	if len(group.Bans) != 2 || !group.IsBanned("member") || group.IsBanned("other") {
+		t.Errorf("unexpected bans %v", group.Bans)
+	}
+
+	if !group.Unban("member") || group.Unban("member") || group.IsBanned("member") {
+		t.Error("expected the ban to be lifted once")
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	blocklist := &Blocklist{Email: "email"}
+
+	blocklist.Block(&Account{Email: "spammer", Tag: "spam", Password: "secret"})
+	blocklist.Block(&Account{Email: "spammer"})
+
+	if len(blocklist.Blocked) != 1 || blocklist.Blocked[0].Password != "" {
+		t.Errorf("unexpected blocklist %v", blocklist.Blocked)
+	}
+	if !blocklist.Blocks("spammer") || blocklist.Blocks("friend") {
+		t.Error("unexpected blocks")
+	}
+	if !blocklist.Unblock("spammer") || blocklist.Unblock("spammer") {
+		t.Error("expected the block to be lifted once")
+	}
+}
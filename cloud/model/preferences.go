@@ -0,0 +1,120 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type QuietMode string
+
+const (
+	// QuietSilent delivers pushes without sound or banner, the default.
+	QuietSilent QuietMode = "silent"
+	QuietDrop   QuietMode = "drop"
+	QuietDigest QuietMode = "digest"
+
+	quietLayout = "15:04"
+)
+
+//nolint:gochecknoglobals
+var quietModes = []QuietMode{"", QuietSilent, QuietDrop, QuietDigest}
+
+var ErrPreferences = errors.New("invalid preferences")
+
+// Location returns the timezone of the account, UTC unless set.
+func (p *Preferences) Location() *time.Location {
+	if loc, err := time.LoadLocation(p.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Mode returns the quiet hours mode of the account.
+func (p *Preferences) Mode() QuietMode {
+	if p.QuietMode == "" {
+		return QuietSilent
+	}
+	return p.QuietMode
+}
+
+// Validate returns an error describing what is wrong with the preferences.
+// Quiet hours need both a start and an end, and are rewritten as HH:MM.
+func (p *Preferences) Validate() error {
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrPreferences, p.Timezone)
+	}
+	if (p.QuietStart == "") != (p.QuietEnd == "") {
+		return fmt.Errorf("%w: quiet hours need a start and an end", ErrPreferences)
+	}
+	for _, v := range []*string{&p.QuietStart, &p.QuietEnd} {
+		if *v == "" {
+			continue
+		}
+		parsed, err := time.Parse(quietLayout, *v)
+		if err != nil {
+			return fmt.Errorf("%w: quiet hours are not HH:MM: %q", ErrPreferences, *v)
+		}
+		*v = parsed.Format(quietLayout)
+	}
+	if !slices.Contains(quietModes, p.QuietMode) {
+		return fmt.Errorf("%w: unknown quiet mode %q", ErrPreferences, p.QuietMode)
+	}
+	return nil
+}
+
+// Quiet returns true if the time is within the quiet hours of the account.
+func (p *Preferences) Quiet(now time.Time) bool {
+	start, err := quietMinutes(p.QuietStart)
+	if err != nil {
+		return false
+	}
+	end, err := quietMinutes(p.QuietEnd)
+	if err != nil || start == end {
+		return false
+	}
+
+	now = now.In(p.Location())
+	clock := now.Hour()*60 + now.Minute()
+	if start < end {
+		return clock >= start && clock < end
+	}
+	return clock >= start || clock < end
+}
+
+// quietMinutes returns the minutes since midnight of a quiet hours time.
+func quietMinutes(v string) (int, error) {
+	parsed, err := time.Parse(quietLayout, v)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Add counts a deferred push from the group.
+func (d *Digest) Add(group *Group, now int64) {
+	for _, item := range d.Items {
+		if item.GroupID == group.ID {
+			item.Count++
+			item.Last = now
+			return
+		}
+	}
+	d.Items = append(d.Items, &DigestItem{
+		GroupID:   group.ID,
+		GroupName: group.Name,
+		Count:     1,
+		Last:      now,
+	})
+}
+
+// Summary describes the deferred pushes, like "3 from Friends, 1 from Work".
+func (d *Digest) Summary() string {
+	parts := make([]string, 0, len(d.Items))
+	for _, item := range d.Items {
+		parts = append(parts, fmt.Sprintf("%d from %s", item.Count, item.GroupName))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPreferencesQuiet(t *testing.T) {
+	night := &Preferences{Timezone: "Europe/Stockholm", QuietStart: "22:00", QuietEnd: "07:00"}
+	lunch := &Preferences{QuietStart: "12:00", QuietEnd: "13:00"}
+	morning := &Preferences{QuietStart: "23:00", QuietEnd: "7:00"}
+
+	tests := []struct {
+		name  string
+		prefs *Preferences
+		now   time.Time
+		want  bool
+	}{
+		{name: "no quiet hours", prefs: &Preferences{}, now: time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC)},
+		// 02:00 UTC is 03:00 in Stockholm in winter.
+		{name: "night", prefs: night, now: time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC), want: true},
+		{name: "night start", prefs: night, now: time.Date(2025, 1, 1, 21, 0, 0, 0, time.UTC), want: true},
+		{name: "night end", prefs: night, now: time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC)},
+		{name: "evening", prefs: night, now: time.Date(2025, 1, 1, 20, 59, 0, 0, time.UTC)},
+		{name: "lunch", prefs: lunch, now: time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC), want: true},
+		{name: "after lunch", prefs: lunch, now: time.Date(2025, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{name: "single digit hour", prefs: morning, now: time.Date(2025, 1, 1, 6, 59, 0, 0, time.UTC), want: true},
+		{name: "after single digit hour", prefs: morning, now: time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := tt.prefs.Quiet(tt.now); got != tt.want {
+			t.Errorf("%s: got quiet %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPreferencesValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		prefs *Preferences
+		valid bool
+	}{
+		{name: "empty", prefs: &Preferences{}, valid: true},
+		{name: "full", prefs: &Preferences{
+			Timezone: "America/New_York", QuietStart: "23:30", QuietEnd: "06:00", QuietMode: QuietDigest,
+		}, valid: true},
+		{name: "timezone", prefs: &Preferences{Timezone: "Mars/Olympus"}},
+		{name: "start only", prefs: &Preferences{QuietStart: "22:00"}},
+		{name: "format", prefs: &Preferences{QuietStart: "10pm", QuietEnd: "07:00"}},
+		{name: "mode", prefs: &Preferences{QuietMode: "loud"}},
+	}
+	for _, tt := range tests {
+		err := tt.prefs.Validate()
+		if tt.valid && err != nil || !tt.valid && !errors.Is(err, ErrPreferences) {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+
+	prefs := &Preferences{QuietStart: "7:00", QuietEnd: "9:05"}
+	if err := prefs.Validate(); err != nil {
+		t.Fatalf("got %v for single digit quiet hours", err)
+	}
+	if prefs.QuietStart != "07:00" || prefs.QuietEnd != "09:05" {
+		t.Errorf("got quiet hours %s-%s, want 07:00-09:05", prefs.QuietStart, prefs.QuietEnd)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	digest := &Digest{}
+	digest.Add(&Group{ID: 1, Name: "Friends"}, 1)
+	digest.Add(&Group{ID: 2, Name: "Work"}, 2)
+	digest.Add(&Group{ID: 1, Name: "Friends"}, 3)
+
+	if got, want := digest.Summary(), "2 from Friends, 1 from Work"; got != want {
+		t.Errorf("got summary %q, want %q", got, want)
+	}
+	if digest.Items[0].Last != 3 {
+		t.Errorf("got last %d, want 3", digest.Items[0].Last)
+	}
+}
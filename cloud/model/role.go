@@ -0,0 +1,119 @@
+package model
+
+import (
+	"cmp"
+	"slices"
+)
+
+type (
+	Role       string
+	Permission string
+)
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+
+	PermissionInvite      Permission = "invite"
+	PermissionKick        Permission = "kick"
+	PermissionEdit        Permission = "edit"
+	PermissionDelete      Permission = "delete"
+	PermissionManageRoles Permission = "manage_roles"
+)
+
+// permissions is the permission matrix of the group roles.
+//
+//nolint:gochecknoglobals
+var permissions = map[Role][]Permission{
+	RoleOwner: {
+		PermissionInvite,
+		PermissionKick,
+		PermissionEdit,
+		PermissionDelete,
+		PermissionManageRoles,
+	},
+	RoleAdmin: {
+		PermissionInvite,
+		PermissionKick,
+		PermissionEdit,
+	},
+	RoleMember: {},
+}
+
+//nolint:gochecknoglobals
+var rank = map[Role]int{
+	RoleOwner:  2,
+	RoleAdmin:  1,
+	RoleMember: 0,
+}
+
+// Member returns the membership of the email, or nil if the email is not a
+// member. The owner has no membership entry.
+func (g *Group) Member(email string) *Member {
+	for _, member := range g.Members {
+		if member.Email == email {
+			return member
+		}
+	}
+	return nil
+}
+
+// IsMember returns true for the owner and all members.
+func (g *Group) IsMember(email string) bool {
+	return g.Owner == email || g.Member(email) != nil
+}
+
+// RoleOf returns the role of the email in the group, or an empty role if the
+// email is not a member.
+func (g *Group) RoleOf(email string) Role {
+	if g.Owner == email {
+		return RoleOwner
+	}
+	if member := g.Member(email); member != nil {
+		if member.Role == "" {
+			return RoleMember
+		}
+		return member.Role
+	}
+	return ""
+}
+
+// LongestStandingMember returns the member that joined first, or nil if there
+// are no members.
+func (g *Group) LongestStandingMember() *Member {
+	if len(g.Members) == 0 {
+		return nil
+	}
+	// MinFunc returns the first of equal members, members are kept in join
+	// order.
+	return slices.MinFunc(g.Members, func(a, b *Member) int {
+		return cmp.Compare(a.Joined, b.Joined)
+	})
+}
+
+// TransferOwnership makes the member with the email the owner, dropping its
+// membership entry but keeping its settings. The previous owner is not kept as
+// a member.
+func (g *Group) TransferOwnership(email string) {
+	g.OwnerSettings = nil
+	if member := g.Member(email); member != nil {
+		settings := member.MemberSettings
+		g.OwnerSettings = &settings
+	}
+	g.Members = slices.DeleteFunc(g.Members, func(m *Member) bool { return m.Email == email })
+	g.Owner = email
+	g.PendingOwner = ""
+}
+
+// Can returns true if the email has the permission in the group.
+func (g *Group) Can(email string, permission Permission) bool {
+	return slices.Contains(permissions[g.RoleOf(email)], permission)
+}
+
+// Outranks returns true if the role of email is strictly higher than that of
+// target, for example, an admin may kick a member but not another admin.
+func (g *Group) Outranks(email, target string) bool {
+	role, targetRole := g.RoleOf(email), g.RoleOf(target)
+	return role != "" && targetRole != "" && rank[role] > rank[targetRole]
+}
@@ -0,0 +1,70 @@
+package model
+
+import "testing"
+
+func TestGroupRoles(t *testing.T) {
+	group := &Group{
+		Owner: "owner",
+		Members: []*Member{
+			{Email: "admin", Role: RoleAdmin},
+			{Email: "member", Role: RoleMember},
+			{Email: "legacy"},
+		},
+	}
+
+	roles := map[string]Role{
+		"owner":    RoleOwner,
+		"admin":    RoleAdmin,
+		"member":   RoleMember,
+		"legacy":   RoleMember,
+		"stranger": "",
+	}
+	for email, want := range roles {
+		if got := group.RoleOf(email); got != want {
+			t.Errorf("%s: got role %q, want %q", email, got, want)
+		}
+	}
+
+	if !group.Can("admin", PermissionKick) || group.Can("admin", PermissionDelete) {
+		t.Error("admins should kick but not delete")
+	}
+	if group.Can("member", PermissionInvite) || group.Can("stranger", PermissionInvite) {
+		t.Error("members and strangers should not invite")
+	}
+	if !group.Can("owner", PermissionManageRoles) {
+		t.Error("owner should manage roles")
+	}
+
+	if !group.Outranks("admin", "member") || group.Outranks("admin", "owner") ||
+		group.Outranks("admin", "admin") || group.Outranks("member", "legacy") {
+		t.Error("unexpected ranking")
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	group := &Group{
+		Owner:        "owner",
+		PendingOwner: "second",
+		Members: []*Member{
+			{Email: "third", Joined: 3},
+			{Email: "first", Joined: 1},
+			{Email: "second", Joined: 1},
+		},
+	}
+
+	if got := group.LongestStandingMember(); got.Email != "first" {
+		t.Errorf("got longest-standing member %s, want %s", got.Email, "first")
+	}
+
+	group.TransferOwnership("first")
+	if group.Owner != "first" || group.PendingOwner != "" {
+		t.Errorf("got owner %q and pending owner %q", group.Owner, group.PendingOwner)
+	}
+	if group.Member("first") != nil || group.IsMember("owner") {
+		t.Error("owner should not be kept as a member")
+	}
+
+	if (&Group{}).LongestStandingMember() != nil {
+		t.Error("group without members should have no longest-standing member")
+	}
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrScheduleTime     = errors.New("schedule needs either a future time or a cron expression")
+	ErrScheduleTimezone = errors.New("unknown schedule timezone")
+)
+
+// Tapp returns the tapp the schedule makes, without time, group or user.
+func (s *Schedule) Tapp() *Tapp {
+	return &Tapp{Type: s.Type, Emoji: s.Emoji, Message: s.Message}
+}
+
+// Location returns the timezone of the schedule, UTC unless set.
+func (s *Schedule) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, errors.Join(ErrScheduleTimezone, err)
+	}
+	return loc, nil
+}
+
+// Validate returns an error describing why the schedule can not run, at the
+// time now in UNIX millis.
+func (s *Schedule) Validate(now int64) error {
+	if (s.At == 0) == (s.Cron == "") || (s.At != 0 && s.At <= now) {
+		return ErrScheduleTime
+	}
+	if s.Cron != "" {
+		if _, err := ParseCron(s.Cron); err != nil {
+			return err
+		}
+	}
+	if _, err := s.Location(); err != nil {
+		return err
+	}
+	return s.Tapp().Validate()
+}
+
+// NextRun returns the time, in UNIX millis, the schedule runs next after the
+// time, or zero if it never runs again.
+func (s *Schedule) NextRun(after time.Time) int64 {
+	if s.Cron == "" {
+		if s.At > after.UnixMilli() {
+			return s.At
+		}
+		return 0
+	}
+
+	cron, err := ParseCron(s.Cron)
+	if err != nil {
+		return 0
+	}
+	loc, err := s.Location()
+	if err != nil {
+		return 0
+	}
+
+	next := cron.Next(after.In(loc))
+	if next.IsZero() {
+		return 0
+	}
+	return next.UnixMilli()
+}
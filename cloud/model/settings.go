@@ -0,0 +1,53 @@
+package model
+
+import "slices"
+
+type NotifyLevel string
+
+const (
+	NotifyAll      NotifyLevel = "all"
+	NotifyMentions NotifyLevel = "mentions"
+	NotifyNone     NotifyLevel = "none"
+)
+
+//nolint:gochecknoglobals
+var notifyLevels = []NotifyLevel{"", NotifyAll, NotifyMentions, NotifyNone}
+
+// Valid returns true for the known notification levels, empty means all.
+func (l NotifyLevel) Valid() bool {
+	return slices.Contains(notifyLevels, l)
+}
+
+// SettingsOf returns the settings of the email in the group, or nil if the
+// email is not a member. The settings can be changed in place, except for the
+// default settings of an owner without OwnerSettings. The group is never
+// modified, groups of events are shared between subscribers.
+func (g *Group) SettingsOf(email string) *MemberSettings {
+	if g.Owner == email {
+		if g.OwnerSettings == nil {
+			return &MemberSettings{}
+		}
+		return g.OwnerSettings
+	}
+	if member := g.Member(email); member != nil {
+		return &member.MemberSettings
+	}
+	return nil
+}
+
+// Notified returns true if a push should reach the member at the time now, in
+// UNIX millis. Mentioned tells if the push targets the member specifically.
+func (s *MemberSettings) Notified(now int64, mentioned bool) bool {
+	if s.MutedUntil > now {
+		return false
+	}
+
+	switch s.Notify {
+	case NotifyNone:
+		return false
+	case NotifyMentions:
+		return mentioned
+	default:
+		return true
+	}
+}
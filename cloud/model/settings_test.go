@@ -0,0 +1,51 @@
+package model
+
+import "testing"
+
+func TestMemberSettings(t *testing.T) {
+	group := &Group{
+		Owner:   "owner",
+		Members: []*Member{{Email: "member"}},
+	}
+
+	if group.SettingsOf("stranger") != nil {
+		t.Error("strangers should have no settings")
+	}
+
+	group.SettingsOf("owner").Nickname = "boss"
+	if group.OwnerSettings != nil {
+		t.Error("reading the owner settings should not modify the group")
+	}
+
+	group.OwnerSettings = &MemberSettings{}
+	group.SettingsOf("owner").Nickname = "boss"
+	group.SettingsOf("member").Notify = NotifyMentions
+	if group.OwnerSettings.Nickname != "boss" || group.Members[0].Notify != NotifyMentions {
+		t.Error("settings should be changed in place")
+	}
+
+	tests := []struct {
+		name      string
+		settings  MemberSettings
+		mentioned bool
+		want      bool
+	}{
+		{name: "default", want: true},
+		{name: "all", settings: MemberSettings{Notify: NotifyAll}, want: true},
+		{name: "none", settings: MemberSettings{Notify: NotifyNone}, mentioned: true},
+		{name: "mentions", settings: MemberSettings{Notify: NotifyMentions}},
+		{name: "mentioned", settings: MemberSettings{Notify: NotifyMentions}, mentioned: true, want: true},
+		{name: "muted", settings: MemberSettings{MutedUntil: 200}},
+		{name: "mute expired", settings: MemberSettings{MutedUntil: 100}, want: true},
+	}
+	for _, tt := range tests {
+		if got := tt.settings.Notified(100, tt.mentioned); got != tt.want {
+			t.Errorf("%s: got notified %t, want %t", tt.name, got, tt.want)
+		}
+	}
+
+	group.TransferOwnership("member")
+	if group.OwnerSettings.Notify != NotifyMentions {
+		t.Error("the new owner should keep its settings")
+	}
+}
@@ -0,0 +1,180 @@
+package model
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type StatsWindow string
+
+const (
+	StatsDay   StatsWindow = "day"
+	StatsWeek  StatsWindow = "week"
+	StatsMonth StatsWindow = "month"
+
+	// statsDays is the number of days of tapp counts kept, the longest window.
+	statsDays   = 30
+	statsLayout = time.DateOnly
+)
+
+//nolint:gochecknoglobals
+var windowDays = map[StatsWindow]int{
+	StatsDay:   1,
+	StatsWeek:  7,
+	StatsMonth: statsDays,
+}
+
+type (
+	// GroupStats holds the running tapp statistics of a group, updated as
+	// tapps are made. Days are UTC days.
+	GroupStats struct {
+		GroupID int                     `json:"group_id"`
+		Members map[string]*MemberStats `json:"members"`
+		// BuiltThrough is the ID of the last tapp of the log the statistics
+		// were built from, tapps up to it are already counted.
+		BuiltThrough int `json:"built_through,omitempty"`
+	}
+	MemberStats struct {
+		Email string `json:"email"`
+		Tag   string `json:"tag,omitempty"`
+		Total int    `json:"total"`
+		// Days holds the tapp count of each of the last days, by date.
+		Days map[string]int `json:"days"`
+		// Replies is the number of tapps answering another tapp, and
+		// ReplyTime the sum of millis it took to answer them.
+		Replies   int   `json:"replies"`
+		ReplyTime int64 `json:"reply_time"`
+		// Streak is the number of days in a row, up to LastDay, with a tapp.
+		LastDay       string `json:"last_day"`
+		Streak        int    `json:"streak"`
+		LongestStreak int    `json:"longest_streak"`
+	}
+	LeaderboardEntry struct {
+		Email         string `json:"email"`
+		Tag           string `json:"tag,omitempty"`
+		Tapps         int    `json:"tapps"`
+		Total         int    `json:"total"`
+		Streak        int    `json:"streak"`
+		LongestStreak int    `json:"longest_streak"`
+		Replies       int    `json:"replies"`
+		// AvgResponseTime is the average number of millis it took the member
+		// to answer a tapp.
+		AvgResponseTime int64 `json:"avg_response_time"`
+	}
+)
+
+func (s *GroupStats) Key() string {
+	return strconv.Itoa(s.GroupID)
+}
+
+// Valid returns true for the known statistics windows.
+func (w StatsWindow) Valid() bool {
+	_, ok := windowDays[w]
+	return ok
+}
+
+// Record adds the tapp to the statistics, original is the tapp it answers, if
+// any.
+func (s *GroupStats) Record(tapp, original *Tapp) {
+	if s.Members == nil {
+		s.Members = map[string]*MemberStats{}
+	}
+
+	member, ok := s.Members[tapp.User.Email]
+	if !ok {
+		member = &MemberStats{Email: tapp.User.Email, Days: map[string]int{}}
+		s.Members[tapp.User.Email] = member
+	}
+	member.Tag = tapp.User.Tag
+	member.Total++
+
+	day := time.UnixMilli(tapp.Time).UTC()
+	member.Days[day.Format(statsLayout)]++
+	for date := range member.Days {
+		if parsed, err := time.Parse(statsLayout, date); err != nil ||
+			day.Sub(parsed) >= statsDays*24*time.Hour {
+			delete(member.Days, date)
+		}
+	}
+
+	if original != nil {
+		member.Replies++
+		member.ReplyTime += tapp.Time - original.Time
+	}
+
+	// Dates in the layout sort like strings, tapps recorded late do not
+	// touch the streak.
+	date := day.Format(statsLayout)
+	switch {
+	case date <= member.LastDay:
+	case member.LastDay == day.AddDate(0, 0, -1).Format(statsLayout):
+		member.Streak++
+		member.LastDay = date
+	default:
+		member.Streak = 1
+		member.LastDay = date
+	}
+	member.LongestStreak = max(member.LongestStreak, member.Streak)
+}
+
+// Count returns the number of tapps of the member in the window ending at now.
+func (m *MemberStats) Count(window StatsWindow, now time.Time) int {
+	count := 0
+	day := now.UTC()
+	for range windowDays[window] {
+		count += m.Days[day.Format(statsLayout)]
+		day = day.AddDate(0, 0, -1)
+	}
+	return count
+}
+
+// CurrentStreak returns the streak of the member at now, which is broken if
+// the member tapped neither today nor yesterday.
+func (m *MemberStats) CurrentStreak(now time.Time) int {
+	today := now.UTC()
+	if m.LastDay != today.Format(statsLayout) &&
+		m.LastDay != today.AddDate(0, 0, -1).Format(statsLayout) {
+		return 0
+	}
+	return m.Streak
+}
+
+// Leaderboard ranks the current members of the group by their tapps in the
+// window, then by their total tapps. Members that never tapped are listed
+// last.
+func (s *GroupStats) Leaderboard(
+	group *Group,
+	window StatsWindow,
+	now time.Time,
+) []*LeaderboardEntry {
+	emails := append([]string{group.Owner}, emailsOf(group.Members)...)
+
+	entries := make([]*LeaderboardEntry, 0, len(emails))
+	for _, email := range emails {
+		entry := &LeaderboardEntry{Email: email}
+		if member := group.Member(email); member != nil {
+			entry.Tag = member.Tag
+		}
+
+		if stats, ok := s.Members[email]; ok {
+			// The member tag is kept current, the stats one is from the last tapp.
+			entry.Tag = cmp.Or(entry.Tag, stats.Tag)
+			entry.Tapps = stats.Count(window, now)
+			entry.Total = stats.Total
+			entry.Streak = stats.CurrentStreak(now)
+			entry.LongestStreak = stats.LongestStreak
+			entry.Replies = stats.Replies
+			if stats.Replies > 0 {
+				entry.AvgResponseTime = stats.ReplyTime / int64(stats.Replies)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	slices.SortStableFunc(entries, func(a, b *LeaderboardEntry) int {
+		return cmp.Or(cmp.Compare(b.Tapps, a.Tapps), cmp.Compare(b.Total, a.Total))
+	})
+	return entries
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupStats(t *testing.T) {
+	day := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(days int) int64 {
+		return day.AddDate(0, 0, days).UnixMilli()
+	}
+	alice := &Account{Email: "alice", Tag: "al"}
+	bob := &Account{Email: "bob", Tag: "b"}
+
+	stats := &GroupStats{GroupID: 1}
+	first := &Tapp{ID: 1, Time: at(-40), User: alice}
+	stats.Record(first, nil)
+	stats.Record(&Tapp{Time: at(-40) + 4000, User: bob, ReplyTo: 1}, first)
+	for _, days := range []int{-3, -2, -2, -1, 0} {
+		stats.Record(&Tapp{Time: at(days), User: alice}, nil)
+	}
+
+	a := stats.Members["alice"]
+	if a.Total != 6 || len(a.Days) != 4 {
+		t.Errorf("got %d tapps over %d days, want 6 over 4", a.Total, len(a.Days))
+	}
+	if a.Streak != 4 || a.LongestStreak != 4 || a.CurrentStreak(day.AddDate(0, 0, 2)) != 0 {
+		t.Errorf("unexpected streaks: %+v", a)
+	}
+
+	counts := map[StatsWindow]int{StatsDay: 1, StatsWeek: 5, StatsMonth: 5}
+	for window, want := range counts {
+		if got := a.Count(window, day); got != want {
+			t.Errorf("%s: got %d tapps, want %d", window, got, want)
+		}
+	}
+
+	// Bob has changed tag since the last tapp.
+	group := &Group{Owner: "alice", Members: []*Member{{Email: "bob", Tag: "bobby"}, {Email: "carol"}}}
+	board := stats.Leaderboard(group, StatsMonth, day)
+	if len(board) != 3 || board[0].Email != "alice" || board[0].Tag != "al" ||
+		board[1].Email != "bob" || board[1].Tag != "bobby" || board[1].AvgResponseTime != 4000 ||
+		board[2].Total != 0 {
+		t.Errorf("unexpected leaderboard: %+v %+v %+v", board[0], board[1], board[2])
+	}
+}
@@ -0,0 +1,132 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type TappType string
+
+const (
+	TappTypeTapp     TappType = "tapp"
+	TappTypeTappBack TappType = "tapp-back"
+	TappTypeUrgent   TappType = "urgent"
+	TappTypeEmoji    TappType = "emoji"
+
+	MaxTappMessageLength = 140
+	maxTappEmojiLength   = 8
+)
+
+//nolint:gochecknoglobals
+var tappTypes = []TappType{TappTypeTapp, TappTypeTappBack, TappTypeUrgent, TappTypeEmoji}
+
+var (
+	ErrTappType    = errors.New("unknown tapp type")
+	ErrTappEmoji   = errors.New("emoji tapps need a single emoji, other tapps none")
+	ErrTappMessage = errors.New("tapp message is too long or contains control characters")
+	ErrTappReply   = errors.New("tapp reply does not reference a tapp")
+)
+
+// Valid returns true for the known tapp types.
+func (t TappType) Valid() bool {
+	return slices.Contains(tappTypes, t)
+}
+
+// TypeOrDefault returns the type of the tapp. Replies are tapp-backs and tapps
+// stored before types were introduced are plain tapps.
+func (t *Tapp) TypeOrDefault() TappType {
+	switch {
+	case t.Type != "":
+		return t.Type
+	case t.ReplyTo != 0:
+		return TappTypeTappBack
+	default:
+		return TappTypeTapp
+	}
+}
+
+// Validate returns an error describing why the type, emoji or message of the
+// tapp is not acceptable.
+func (t *Tapp) Validate() error {
+	if !t.TypeOrDefault().Valid() {
+		return ErrTappType
+	}
+	if (t.Type == TappTypeEmoji) != (t.Emoji != "") || (t.Emoji != "" && !isEmoji(t.Emoji)) {
+		return ErrTappEmoji
+	}
+	if utf8.RuneCountInString(t.Message) > MaxTappMessageLength ||
+		strings.ContainsFunc(t.Message, unicode.IsControl) {
+		return ErrTappMessage
+	}
+	if t.ReplyTo < 0 {
+		return ErrTappReply
+	}
+	return nil
+}
+
+// MentionedTags returns the tags mentioned in the message with an @, without
+// the @ and surrounding punctuation.
+func (t *Tapp) MentionedTags() []string {
+	tags := []string{}
+	for _, word := range strings.Fields(t.Message) {
+		tag, ok := strings.CutPrefix(word, "@")
+		tag = strings.TrimRightFunc(tag, unicode.IsPunct)
+		if ok && tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// isEmoji returns true if s is a short sequence of symbols and the modifiers
+// and joiners emoji are built from.
+func isEmoji(s string) bool {
+	if utf8.RuneCountInString(s) > maxTappEmojiLength {
+		return false
+	}
+
+	symbols := 0
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.So, r):
+			symbols++
+		case unicode.In(r, unicode.Sk, unicode.Mn, unicode.Me, unicode.Cf):
+		default:
+			return false
+		}
+	}
+	return symbols > 0
+}
+
+// NumberTapps sets the IDs of the tapps of a group log from their positions,
+// tapps stored before IDs were introduced have none.
+func NumberTapps(tapps []*Tapp) {
+	for i, t := range tapps {
+		if t.ID == 0 {
+			t.ID = i + 1
+		}
+	}
+}
+
+// Thread returns the tapp with the ID and its replies from the numbered group
+// log, or nil if there is no such tapp.
+func Thread(tapps []*Tapp, id int) *TappThread {
+	if id < 1 || id > len(tapps) {
+		return nil
+	}
+
+	thread := &TappThread{Tapp: tapps[id-1], Replies: []*TappReply{}}
+	// Replies are always logged after the tapp they answer.
+	for _, t := range tapps[id:] {
+		if t.ReplyTo == id {
+			thread.Replies = append(thread.Replies, &TappReply{
+				Tapp:         t,
+				ResponseTime: t.Time - thread.Tapp.Time,
+			})
+		}
+	}
+	return thread
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTappValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		tapp *Tapp
+		want error
+	}{
+		{name: "Legacy", tapp: &Tapp{}},
+		{name: "Tapp back", tapp: &Tapp{Type: TappTypeTappBack, Message: "Right back at you"}},
+		{name: "Urgent", tapp: &Tapp{Type: TappTypeUrgent}},
+		{name: "Emoji", tapp: &Tapp{Type: TappTypeEmoji, Emoji: "🎉"}},
+		{name: "Joined emoji", tapp: &Tapp{Type: TappTypeEmoji, Emoji: "👩‍👩‍👧"}},
+		{name: "Unknown type", tapp: &Tapp{Type: "poke"}, want: ErrTappType},
+		{name: "Emoji missing", tapp: &Tapp{Type: TappTypeEmoji}, want: ErrTappEmoji},
+		{name: "Emoji on tapp", tapp: &Tapp{Emoji: "🎉"}, want: ErrTappEmoji},
+		{name: "Text emoji", tapp: &Tapp{Type: TappTypeEmoji, Emoji: "hi"}, want: ErrTappEmoji},
+		{name: "Long message", tapp: &Tapp{Message: strings.Repeat("a", 141)}, want: ErrTappMessage},
+		{name: "Control message", tapp: &Tapp{Message: "a\nb"}, want: ErrTappMessage},
+	}
+	for _, tt := range tests {
+		if got := tt.tapp.Validate(); got != tt.want {
+			t.Errorf("%s: got error %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTappMentionedTags(t *testing.T) {
+	tapp := &Tapp{Message: "@alice and @bob, lunch? @alice @"}
+	if got := tapp.MentionedTags(); !slices.Equal(got, []string{"alice", "bob"}) {
+		t.Errorf("got mentioned tags %v", got)
+	}
+}
+
+func TestTappThread(t *testing.T) {
+	tapps := []*Tapp{
+		{Time: 1000},
+		{Time: 2000, ReplyTo: 1},
+		{ID: 3, Time: 2500},
+		{Time: 4000, ReplyTo: 1},
+		{Time: 4500, ReplyTo: 3},
+	}
+	NumberTapps(tapps)
+
+	if tapps[1].ID != 2 || tapps[4].ID != 5 {
+		t.Fatalf("unexpected tapp IDs %d and %d", tapps[1].ID, tapps[4].ID)
+	}
+	if tapps[1].TypeOrDefault() != TappTypeTappBack {
+		t.Errorf("got reply type %s, want %s", tapps[1].TypeOrDefault(), TappTypeTappBack)
+	}
+
+	thread := Thread(tapps, 1)
+	if len(thread.Replies) != 2 || thread.Replies[0].ResponseTime != 1000 ||
+		thread.Replies[1].ResponseTime != 3000 {
+		t.Errorf("unexpected thread: %+v", thread.Replies)
+	}
+
+	if Thread(tapps, 0) != nil || Thread(tapps, 6) != nil {
+		t.Error("expected no thread for unknown tapps")
+	}
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/trebent/tapp-backend/env"
+	"github.com/trebent/zerologr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "tapp-backend"
+
+// Initialize sets up the global tracer provider according to TRACE_EXPORTER.
+// The returned function flushes and stops the exporter, and should be called
+// on shutdown.
+func Initialize(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch env.TraceExporter.Value() {
+	case "stdout":
+		exporter, err = stdouttrace.New()
+	case "otlp":
+		exporter, err = otlptracegrpc.New(ctx,
+			otlptracegrpc.WithEndpoint(env.OTLPEndpoint.Value()),
+			otlptracegrpc.WithInsecure(),
+		)
+	default:
+		// Spans are still created, so trace IDs end up in the logs, but they are
+		// never exported.
+		provider := sdktrace.NewTracerProvider()
+		otel.SetTracerProvider(provider)
+		return provider.Shutdown, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(
+			resource.NewSchemaless(attribute.String("service.name", serviceName)),
+		),
+	)
+	otel.SetTracerProvider(provider)
+
+	zerologr.Info("tracing initialized", "exporter", env.TraceExporter.Value())
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns a named tracer from the global provider.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer("github.com/trebent/tapp-backend/" + name)
+}
+
+// Logger returns a logger that adds the trace and span IDs of the span in ctx
+// to every log line.
+func Logger(ctx context.Context) logr.Logger {
+	logger := zerologr.V(0)
+
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return logger
+	}
+
+	return logger.WithValues(
+		"trace_id", spanCtx.TraceID().String(),
+		"span_id", spanCtx.SpanID().String(),
+	)
+}